@@ -95,34 +95,114 @@ func executeRootCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	httpClient, err := core.NewHTTPClient(cfg.Network.HTTP)
+	if err != nil {
+		return err
+	}
+
+	core.SetDefaultHTTPClient(httpClient)
+
 	alerters := alerts.CreateAlerters(cfg, baseLogger)
-	monitors := monitor.CreateMonitors(cfg, baseLogger)
 
 	db, err := core.NewBadgerDB(cfg, baseLogger)
 	if err != nil {
 		return err
 	}
 
+	chains := make([]*chain, 0, len(cfg.ChainConfigs()))
+
+	for _, chainCfg := range cfg.ChainConfigs() {
+		c, err := startChain(chainCfg, baseLogger, db, alerters)
+		if err != nil {
+			cleanup(db, nil, httpClient, chains)
+			return err
+		}
+
+		chains = append(chains, c)
+	}
+
 	srvr, err := server.CreateServer(cfg, db, baseLogger)
 	if err != nil {
+		cleanup(db, nil, httpClient, chains)
 		return err
 	}
 
-	mngr := manager.New(baseLogger, db, cfg, monitors, alerters)
-
 	baseLogger.Info("starting Titan!")
-	go mngr.Start()
+	for _, c := range chains {
+		go c.mngr.Start()
+	}
 
 	done := make(chan bool, 1)
 
 	handleSigs(done)
 	<-done
 	baseLogger.Info("cleaning up and exiting...")
-	cleanup(db, srvr)
+	cleanup(db, srvr, httpClient, chains)
 
 	return nil
 }
 
+// chain defines the running components of a single monitored chain.
+type chain struct {
+	clients *core.ClientManager
+	events  *monitor.EventSource
+	metrics *monitor.MetricsCollector
+	mngr    manager.Manager
+}
+
+// startChain starts the LCD client manager of a single chain's configuration,
+// resolves its validator filters through it and starts its metrics collector
+// and optional event source. It returns the chain along with the manager of
+// its monitors, which is yet to be started.
+func startChain(cfg config.Config, baseLogger core.Logger, db core.DB, alerters []alerts.Alerter) (*chain, error) {
+	logger := baseLogger
+	if cfg.ChainID != "" {
+		logger = logger.With("chain", cfg.ChainID)
+	}
+
+	c := &chain{clients: monitor.NewLCDClientManager(cfg, logger)}
+
+	resolutions, err := monitor.ResolveValidatorFilters(cfg, logger, c.clients)
+	if err != nil {
+		c.clients.Stop()
+
+		if cfg.ChainID != "" {
+			return nil, fmt.Errorf("chain %s: %v", cfg.ChainID, err)
+		}
+
+		return nil, err
+	}
+
+	for i, res := range resolutions {
+		cfg.Filters.Validators[i] = res.Filter
+
+		if res.Warning != "" {
+			logger.Warnf(
+				"validator %s resolved to address %s (source: %s): %s",
+				res.Filter.Operator, res.Filter.Address, res.Source, res.Warning,
+			)
+		} else {
+			logger.Infof(
+				"validator %s resolved to address %s (source: %s)",
+				res.Filter.Operator, res.Filter.Address, res.Source,
+			)
+		}
+	}
+
+	if len(cfg.Network.RPCClients) != 0 {
+		c.events = monitor.NewEventSource(cfg, logger)
+		c.events.Start()
+	}
+
+	c.metrics = monitor.NewMetricsCollector(cfg, logger, c.clients)
+	c.metrics.Start()
+
+	monitors := monitor.CreateMonitors(cfg, logger, c.clients, c.events)
+	c.mngr = manager.New(logger, db, cfg, monitors, alerters)
+
+	return c, nil
+}
+
 // Execute executes the application root command. If any error is returned, it
 // is printed to STDOUT and a non-zero exit status is returned.
 func Execute() {
@@ -131,9 +211,25 @@ func Execute() {
 	}
 }
 
-func cleanup(db core.DB, srvr *server.Server) {
+func cleanup(
+	db core.DB, srvr *server.Server, httpClient *core.HTTPClient, chains []*chain,
+) {
+	for _, c := range chains {
+		if c.events != nil {
+			c.events.Stop()
+		}
+
+		c.metrics.Stop()
+
+		c.clients.Stop()
+	}
+
+	httpClient.Close()
+
 	db.Close()
-	srvr.Close()
+	if srvr != nil {
+		srvr.Close()
+	}
 }
 
 func handleSigs(done chan<- bool) {
@@ -3,35 +3,99 @@ package config
 import (
 	"errors"
 	"fmt"
+	"strings"
 
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/tendermint/tendermint/libs/bech32"
 	"gopkg.in/go-playground/validator.v9"
 )
 
 // Valid monitor configuration value constants.
 const (
-	MonitorAll               = "*"
-	MonitorNewProposals      = "new_proposals"
-	MonitorActiveProposals   = "active_proposals"
-	MonitorJailedValidators  = "jailed_validators"
-	MonitorDoubleSigning     = "double_signing"
-	MonitorMissingSignatures = "missing_signatures"
+	MonitorAll                  = "*"
+	MonitorNewProposals         = "new_proposals"
+	MonitorActiveProposals      = "active_proposals"
+	MonitorJailedValidators     = "jailed_validators"
+	MonitorDoubleSigning        = "double_signing"
+	MonitorMissingSignatures    = "missing_signatures"
+	MonitorMissingVotes         = "missing_votes"
+	MonitorProposalOutcomes     = "proposal_outcomes"
+	MonitorSoftwareUpgrades     = "software_upgrades"
+	MonitorLowBalances          = "low_balances"
+	MonitorLowUptime            = "low_uptime"
+	MonitorConsensusKeyChanges  = "consensus_key_changes"
+	MonitorUndelegations        = "undelegations"
+	MonitorLowSelfDelegation    = "low_self_delegation"
+	MonitorNetworkDoubleSigning = "network_double_signing"
+	MonitorConsensusRounds      = "consensus_rounds"
+	MonitorMissingProposals     = "missing_proposals"
+	MonitorMempoolSaturation    = "mempool_saturation"
+	MonitorClientDisagreements  = "client_disagreements"
+	MonitorClientMismatches     = "client_chain_mismatches"
+)
+
+// Valid LCD API configuration value constants. The legacy API is the LCD of
+// Cosmos SDK v0.24 and the gateway API the gRPC-gateway REST endpoints of
+// current Cosmos SDK versions.
+const (
+	APILegacy  = "legacy"
+	APIGateway = "gateway"
+)
+
+// Default Bech32 prefixes of the legacy API (Cosmos SDK v0.24) and of the
+// gateway API (current Cosmos SDK versions). Validator operators of the legacy
+// API are account addresses.
+var (
+	LegacyBech32Prefixes = Bech32{
+		AccAddr:  "cosmosaccaddr",
+		ValAddr:  "cosmosaccaddr",
+		ConsAddr: "cosmosvaladdr",
+		ConsPub:  "cosmosvalpub",
+	}
+
+	DefaultBech32Prefixes = Bech32{
+		AccAddr:  "cosmos",
+		ValAddr:  "cosmosvaloper",
+		ConsAddr: "cosmosvalcons",
+		ConsPub:  "cosmosvalconspub",
+	}
 )
 
 var (
 	structValidate = validator.New()
 	validValues    = map[string]struct{}{
-		MonitorAll:               struct{}{},
-		MonitorNewProposals:      struct{}{},
-		MonitorActiveProposals:   struct{}{},
-		MonitorJailedValidators:  struct{}{},
-		MonitorDoubleSigning:     struct{}{},
-		MonitorMissingSignatures: struct{}{},
+		MonitorAll:                  struct{}{},
+		MonitorNewProposals:         struct{}{},
+		MonitorActiveProposals:      struct{}{},
+		MonitorJailedValidators:     struct{}{},
+		MonitorDoubleSigning:        struct{}{},
+		MonitorMissingSignatures:    struct{}{},
+		MonitorMissingVotes:         struct{}{},
+		MonitorProposalOutcomes:     struct{}{},
+		MonitorSoftwareUpgrades:     struct{}{},
+		MonitorLowBalances:          struct{}{},
+		MonitorLowUptime:            struct{}{},
+		MonitorConsensusKeyChanges:  struct{}{},
+		MonitorUndelegations:        struct{}{},
+		MonitorLowSelfDelegation:    struct{}{},
+		MonitorNetworkDoubleSigning: struct{}{},
+		MonitorConsensusRounds:      struct{}{},
+		MonitorMissingProposals:     struct{}{},
+		MonitorMempoolSaturation:    struct{}{},
+		MonitorClientDisagreements:  struct{}{},
+		MonitorClientMismatches:     struct{}{},
 	}
 )
 
 type (
-	// Config defines the application's configuration structure.
+	// Config defines the application's configuration structure. Multiple chains
+	// may be monitored by giving a list of chains, in which case the database,
+	// alert targets, integrations, listen address and HTTP client settings are
+	// shared by all chains and the remaining configuration is given per chain.
+	// Otherwise, a single chain is monitored with an optional chain ID.
 	Config struct {
+		ChainID      string        `mapstructure:"chain_id"`
+		Chains       []ChainConfig `mapstructure:"chains"`
 		PollInterval uint          `mapstructure:"poll_interval" validate:"required,gt=10"`
 		Monitors     []string      `mapstructure:"monitors" validate:"required,validmonitor"`
 		Database     Database      `mapstructure:"database" validate:"required,dive"`
@@ -39,6 +103,27 @@ type (
 		Filters      Filters       `mapstructure:"filters" validate:"required,dive"`
 		Network      NetworkConfig `mapstructure:"network" validate:"required,dive"`
 		Integrations Integrations  `mapstructure:"integrations" validate:"required,dive"`
+		Governance   Governance    `mapstructure:"governance"`
+		Slashing     Slashing      `mapstructure:"slashing"`
+		Staking      Staking       `mapstructure:"staking"`
+		Consensus    Consensus     `mapstructure:"consensus"`
+		Mempool      Mempool       `mapstructure:"mempool"`
+	}
+
+	// ChainConfig defines the configuration of a single chain monitored along
+	// with other chains. The chain ID namespaces the chain's persisted alerts
+	// and monitor executions as well as its REST API routes.
+	ChainConfig struct {
+		ID           string        `mapstructure:"id"`
+		PollInterval uint          `mapstructure:"poll_interval"`
+		Monitors     []string      `mapstructure:"monitors"`
+		Filters      Filters       `mapstructure:"filters"`
+		Network      NetworkConfig `mapstructure:"network"`
+		Governance   Governance    `mapstructure:"governance"`
+		Slashing     Slashing      `mapstructure:"slashing"`
+		Staking      Staking       `mapstructure:"staking"`
+		Consensus    Consensus     `mapstructure:"consensus"`
+		Mempool      Mempool       `mapstructure:"mempool"`
 	}
 
 	// Database defines embedded database configuration.
@@ -46,10 +131,82 @@ type (
 		DataDir string `mapstructure:"data_dir" validate:"required"`
 	}
 
-	// NetworkConfig defines network related configuration.
+	// NetworkConfig defines network related configuration of the LCD and
+	// Tendermint RPC clients a chain is queried through.
 	NetworkConfig struct {
 		ListenAddr string   `mapstructure:"listen_addr" validate:"required,tcp_addr"`
-		Clients    []string `mapstructure:"clients" validate:"gt=0,dive,url"`
+		Clients    []string `mapstructure:"clients" validate:"dive,url"`
+
+		// RPCClients are optional Tendermint RPC endpoints used to subscribe to
+		// block and transaction events over a websocket.
+		RPCClients []string `mapstructure:"rpc_clients" validate:"dive,url"`
+
+		API string `mapstructure:"api" validate:"omitempty,oneof=legacy gateway"`
+
+		// Bech32 prefixes default to those of the configured API and may only be
+		// changed when not using the legacy API.
+		Bech32 Bech32 `mapstructure:"bech32"`
+
+		// ExpectedChainID, if given, is the chain ID every LCD and RPC client's
+		// node must run. It is independent of the chain ID namespacing a chain's
+		// persisted alerts and monitor executions.
+		ExpectedChainID string `mapstructure:"expected_chain_id"`
+
+		// GenesisHash, if given, is the HEX hash of the block at height one the
+		// chain's first block must have.
+		GenesisHash string `mapstructure:"genesis_hash" validate:"omitempty,hexadecimal"`
+
+		// VerifyInterval is the interval (in seconds) at which clients are
+		// verified again after being verified at startup. Clients are excluded
+		// until verified and mismatching clients until verified again.
+		VerifyInterval uint `mapstructure:"verify_interval"`
+
+		// HealthCheckInterval, if given, is the interval (in seconds) at which LCD
+		// clients that are unreachable or lag behind are ejected until they are
+		// healthy again.
+		HealthCheckInterval uint `mapstructure:"health_check_interval"`
+
+		// MaxHeightLag is the number of blocks an LCD client may lag behind the
+		// highest client. A zero value disables the check.
+		MaxHeightLag int64 `mapstructure:"max_height_lag" validate:"gte=0"`
+
+		// Quorum, if greater than one, is the number of healthy LCD clients that
+		// must agree on blocks at a height and validators for a response to be
+		// used.
+		Quorum uint `mapstructure:"quorum"`
+
+		// RateLimit, if given, limits requests to each LCD client to that many
+		// per second with a burst of RateBurst (defaulting to one).
+		RateLimit float64 `mapstructure:"rate_limit" validate:"gte=0"`
+		RateBurst uint    `mapstructure:"rate_burst"`
+
+		HTTP HTTP `mapstructure:"http"`
+	}
+
+	// Bech32 defines the Bech32 prefixes of a network's account, validator
+	// operator and consensus addresses and of consensus public keys. Any prefix
+	// not given defaults to the respective prefix of the configured API.
+	Bech32 struct {
+		AccAddr  string `mapstructure:"acc_addr"`
+		ValAddr  string `mapstructure:"val_addr"`
+		ConsAddr string `mapstructure:"cons_addr"`
+		ConsPub  string `mapstructure:"cons_pub"`
+	}
+
+	// HTTP defines the HTTP client configuration used for all requests made to
+	// LCD and RPC clients. The timeout is in seconds and the retry backoff is in
+	// milliseconds.
+	HTTP struct {
+		Timeout      uint   `mapstructure:"timeout"`
+		Retries      uint   `mapstructure:"retries"`
+		RetryBackoff uint   `mapstructure:"retry_backoff"`
+		CACert       string `mapstructure:"ca_cert" validate:"omitempty,file"`
+		ClientCert   string `mapstructure:"client_cert" validate:"omitempty,file"`
+		ClientKey    string `mapstructure:"client_key" validate:"omitempty,file"`
+		Username     string `mapstructure:"username"`
+		Password     string `mapstructure:"password"`
+		BearerToken  string `mapstructure:"bearer_token"`
+		Proxy        string `mapstructure:"proxy" validate:"omitempty,url"`
 	}
 
 	// Targets defines alerting targets.
@@ -59,16 +216,92 @@ type (
 		EmailRecipients []string `mapstructure:"email_recipients" validate:"dive,email"`
 	}
 
-	// Filters defines a set of validator address filters to match against when
-	// monitoring and alerting.
+	// Filters defines a set of validator and account address filters to match
+	// against when monitoring and alerting.
 	Filters struct {
 		Validators []ValidatorFilter `mapstructure:"validator" validate:"required,dive"`
+		Accounts   []AccountFilter   `mapstructure:"account" validate:"dive"`
 	}
 
-	// ValidatorFilter defines a validator filter against.
+	// ValidatorFilter defines a validator filter against. The consensus address
+	// is optional and is derived at startup from either the consensus public key,
+	// if given, or the validator's operator. The operator and consensus public
+	// key must be encoded with the network's Bech32 prefixes. The minimum
+	// self-delegation defaults to the staking minimum self-delegation if zero.
 	ValidatorFilter struct {
-		Operator string `mapstructure:"operator" validate:"contains=cosmosaccaddr,required"`
-		Address  string `mapstructure:"address" validate:"hexadecimal,required"`
+		Operator          string `mapstructure:"operator" validate:"required"`
+		Address           string `mapstructure:"address" validate:"omitempty,hexadecimal"`
+		PubKey            string `mapstructure:"pubkey"`
+		MinSelfDelegation int64  `mapstructure:"min_self_delegation" validate:"gte=0"`
+	}
+
+	// Governance defines governance monitoring configuration. The voting and
+	// deposit periods (in blocks) and the minimum deposit must match the
+	// network's governance parameters as they are not exposed by the LCD.
+	Governance struct {
+		VotingPeriod      int64  `mapstructure:"voting_period" validate:"gte=0"`
+		VoteReminders     []uint `mapstructure:"vote_reminders" validate:"dive,gt=0"`
+		MaxDepositPeriod  int64  `mapstructure:"max_deposit_period" validate:"gte=0"`
+		MinDeposit        string `mapstructure:"min_deposit"`
+		DepositWarning    uint   `mapstructure:"deposit_warning"`
+		UpgradeCountdowns []uint `mapstructure:"upgrade_countdowns" validate:"dive,gt=0"`
+	}
+
+	// AccountFilter defines an account filter along with the minimum balance
+	// (e.g. "100steak,10photino") the account should hold. The address must be
+	// encoded with the network's Bech32 account prefix.
+	AccountFilter struct {
+		Address    string `mapstructure:"address" validate:"required"`
+		MinBalance string `mapstructure:"min_balance" validate:"required,coins"`
+	}
+
+	// Slashing defines slashing monitoring configuration. The signed blocks
+	// window must match the network's slashing parameters as it is not exposed
+	// by the LCD. The minimum uptime is a percentage of the window. The
+	// network-wide double sign severity is included in the alert's memo.
+	Slashing struct {
+		SignedBlocksWindow        int64   `mapstructure:"signed_blocks_window" validate:"gte=0"`
+		MinUptime                 float64 `mapstructure:"min_uptime" validate:"gte=0,lte=100"`
+		NetworkDoubleSignSeverity string  `mapstructure:"network_double_sign_severity" validate:"omitempty,oneof=info warning critical"`
+	}
+
+	// Consensus defines consensus monitoring configuration. An alert is
+	// triggered when the network reaches the maximum round at a given height. A
+	// zero value disables the check. A filtered validator that has not proposed
+	// a block for longer than the proposal miss factor times its expected
+	// proposal interval, based on its share of the voting power, is alerted on.
+	Consensus struct {
+		MaxRound           int     `mapstructure:"max_round" validate:"gte=0"`
+		ProposalMissFactor float64 `mapstructure:"proposal_miss_factor" validate:"gte=0"`
+	}
+
+	// Staking defines staking monitoring configuration. If a filtered
+	// validator's consensus key changes and the consensus key is followed, all
+	// monitors will use the new consensus address instead of the configured one.
+	// An unbonding delegation or redelegation from a filtered validator is
+	// alerted on if it exceeds the maximum amount of tokens or the maximum
+	// percentage of the validator's bonded tokens. A zero value disables the
+	// respective threshold. A filtered validator's self-delegation is alerted on
+	// if it falls within the margin (as a percentage) above its minimum
+	// self-delegation, which defaults to the minimum self-delegation given here.
+	Staking struct {
+		FollowConsensusKey    bool    `mapstructure:"follow_consensus_key"`
+		MaxUndelegationAmount int64   `mapstructure:"max_undelegation_amount" validate:"gte=0"`
+		MaxUndelegationShare  float64 `mapstructure:"max_undelegation_share" validate:"gte=0,lte=100"`
+		MinSelfDelegation     int64   `mapstructure:"min_self_delegation" validate:"gte=0"`
+		SelfDelegationMargin  float64 `mapstructure:"self_delegation_margin" validate:"gte=0"`
+	}
+
+	// Mempool defines mempool monitoring configuration. A node's mempool is
+	// alerted on if its number of transactions or total size in bytes exceeds
+	// the respective maximum for a number of consecutive polls. The maximum
+	// number of transactions is required as older Tendermint nodes do not report
+	// the total size of their mempool and a zero maximum size disables the
+	// threshold.
+	Mempool struct {
+		MaxTxs         int64 `mapstructure:"max_txs" validate:"gte=0"`
+		MaxBytes       int64 `mapstructure:"max_bytes" validate:"gte=0"`
+		SustainedPolls uint  `mapstructure:"sustained_polls"`
 	}
 
 	// Integrations defines integration configuration for utilizing third-party
@@ -86,6 +319,7 @@ type (
 
 func init() {
 	structValidate.RegisterValidation("validmonitor", validateMonitor)
+	structValidate.RegisterValidation("coins", validateCoins)
 }
 
 // validateMonitor implements the validator.Func interface. It validates if a
@@ -110,20 +344,359 @@ func validateMonitor(fl validator.FieldLevel) bool {
 	return true
 }
 
+// validateCoins implements the validator.Func interface. It validates if a
+// valid non-zero series of coins was given.
+func validateCoins(fl validator.FieldLevel) bool {
+	coins, err := sdk.ParseCoins(fl.Field().String())
+	return err == nil && !coins.IsZero()
+}
+
 // Validate performs basic validation of parsed application configuration. If
-// any validation fails, an error is immediately returned.
+// any validation fails, an error is immediately returned. The configuration of
+// each chain is validated as a single chain configuration.
 func (cfg Config) Validate() error {
+	if len(cfg.Chains) == 0 {
+		if err := cfg.validateChain(); err != nil {
+			return newConfigErr(cfg.ChainID, err)
+		}
+
+		return nil
+	}
+
+	if err := cfg.validateChains(); err != nil {
+		return newConfigErr("", err)
+	}
+
+	for _, chainCfg := range cfg.ChainConfigs() {
+		if err := chainCfg.validateChain(); err != nil {
+			return newConfigErr(chainCfg.ChainID, err)
+		}
+	}
+
+	return nil
+}
+
+// validateChain validates the configuration of a single chain.
+func (cfg Config) validateChain() error {
 	if err := structValidate.Struct(cfg); err != nil {
-		return newConfigErr(err)
+		return err
+	} else if strings.ContainsAny(cfg.ChainID, "/ ") {
+		return fmt.Errorf("invalid chain ID %q", cfg.ChainID)
 	} else if len(cfg.Targets.EmailRecipients) == 0 &&
 		len(cfg.Targets.SMSRecipients) == 0 &&
 		len(cfg.Targets.Webhooks) == 0 {
-		return newConfigErr(errors.New("no alert targets provided"))
+		return errors.New("no alert targets provided")
+	} else if len(cfg.Network.Clients) == 0 {
+		return errors.New("no LCD clients provided")
+	} else if err := cfg.validateMonitors(); err != nil {
+		return err
+	} else if int(cfg.Network.Quorum) > len(cfg.Network.Clients) {
+		return errors.New("quorum exceeds the number of clients")
+	} else if cfg.Network.HTTP.BearerToken != "" && cfg.Network.HTTP.Username != "" {
+		return errors.New("basic and bearer authentication are mutually exclusive")
+	} else if (cfg.Network.HTTP.ClientCert == "") != (cfg.Network.HTTP.ClientKey == "") {
+		return errors.New("client certificate and key must be provided together")
+	} else if err := cfg.Governance.validateDeposit(cfg.Network.LegacyAPI()); err != nil {
+		return err
+	} else if err := cfg.Network.validateBech32(); err != nil {
+		return err
+	}
+
+	return cfg.Filters.validateBech32(cfg.Network.Bech32Prefixes())
+}
+
+// validateChains validates that chain specific configuration is only given per
+// chain and that every chain has a unique ID. Configuration shared by all
+// chains must not be given per chain.
+func (cfg Config) validateChains() error {
+	if cfg.ChainID != "" || cfg.PollInterval != 0 || len(cfg.Monitors) != 0 ||
+		len(cfg.Filters.Validators) != 0 || len(cfg.Filters.Accounts) != 0 ||
+		len(cfg.Network.Clients) != 0 || len(cfg.Network.RPCClients) != 0 {
+		return errors.New("chain configuration must be given per chain when multiple chains are provided")
+	}
+
+	ids := make(map[string]struct{}, len(cfg.Chains))
+
+	for _, chain := range cfg.Chains {
+		if chain.ID == "" {
+			return errors.New("chain ID required")
+		}
+
+		if _, ok := ids[chain.ID]; ok {
+			return fmt.Errorf("duplicate chain ID %s", chain.ID)
+		}
+
+		ids[chain.ID] = struct{}{}
+
+		if chain.Network.ListenAddr != "" || chain.Network.HTTP != (HTTP{}) {
+			return fmt.Errorf("chain %s: listen address and HTTP client settings are shared by all chains", chain.ID)
+		}
+	}
+
+	return nil
+}
+
+// ChainConfigs returns the configuration of each monitored chain as a single
+// chain configuration. Configuration shared by all chains is copied into each
+// chain's configuration. Without a list of chains, the configuration itself is
+// returned.
+func (cfg Config) ChainConfigs() []Config {
+	if len(cfg.Chains) == 0 {
+		return []Config{cfg}
+	}
+
+	cfgs := make([]Config, len(cfg.Chains))
+
+	for i, chain := range cfg.Chains {
+		network := chain.Network
+		network.ListenAddr = cfg.Network.ListenAddr
+		network.HTTP = cfg.Network.HTTP
+
+		cfgs[i] = Config{
+			ChainID:      chain.ID,
+			PollInterval: chain.PollInterval,
+			Monitors:     chain.Monitors,
+			Database:     cfg.Database,
+			Targets:      cfg.Targets,
+			Filters:      chain.Filters,
+			Network:      network,
+			Integrations: cfg.Integrations,
+			Governance:   chain.Governance,
+			Slashing:     chain.Slashing,
+			Staking:      chain.Staking,
+			Consensus:    chain.Consensus,
+			Mempool:      chain.Mempool,
+		}
+	}
+
+	return cfgs
+}
+
+// VerifyClients returns true if clients are verified to serve the expected
+// chain, i.e. an expected chain ID or genesis hash is configured.
+func (cfg Config) VerifyClients() bool {
+	return cfg.Network.ExpectedChainID != "" || cfg.Network.GenesisHash != ""
+}
+
+// LegacyAPI returns true if chain queries are made against the LCD of Cosmos
+// SDK v0.24, i.e. the gateway API is not configured.
+func (nc NetworkConfig) LegacyAPI() bool {
+	return nc.API != APIGateway
+}
+
+// Bech32Prefixes returns the network's Bech32 prefixes where any prefix not
+// configured is set to the default prefix of the configured API.
+func (nc NetworkConfig) Bech32Prefixes() Bech32 {
+	prefixes := DefaultBech32Prefixes
+	if nc.LegacyAPI() {
+		prefixes = LegacyBech32Prefixes
+	}
+
+	if nc.Bech32.AccAddr != "" {
+		prefixes.AccAddr = nc.Bech32.AccAddr
+	}
+
+	if nc.Bech32.ValAddr != "" {
+		prefixes.ValAddr = nc.Bech32.ValAddr
+	}
+
+	if nc.Bech32.ConsAddr != "" {
+		prefixes.ConsAddr = nc.Bech32.ConsAddr
+	}
+
+	if nc.Bech32.ConsPub != "" {
+		prefixes.ConsPub = nc.Bech32.ConsPub
+	}
+
+	return prefixes
+}
+
+// validateBech32 validates that the network's Bech32 prefixes can be encoded.
+// The legacy API only supports the prefixes of Cosmos SDK v0.24 as they are
+// fixed by the encoding of its responses.
+func (nc NetworkConfig) validateBech32() error {
+	prefixes := nc.Bech32Prefixes()
+	if nc.LegacyAPI() && prefixes != LegacyBech32Prefixes {
+		return errors.New("custom Bech32 prefixes are not supported by the legacy API")
+	}
+
+	for _, prefix := range []string{prefixes.AccAddr, prefixes.ValAddr, prefixes.ConsAddr, prefixes.ConsPub} {
+		if _, err := bech32.ConvertAndEncode(prefix, []byte{0}); err != nil {
+			return fmt.Errorf("invalid Bech32 prefix %s: %s", prefix, err)
+		}
 	}
 
 	return nil
 }
 
-func newConfigErr(err error) error {
-	return fmt.Errorf("invalid configuration: \"%s\"", err)
+// validateBech32 validates that all filtered validator operators, consensus
+// public keys and accounts are valid Bech32 encodings with the given prefixes.
+func (f Filters) validateBech32(prefixes Bech32) error {
+	for _, validatorFilter := range f.Validators {
+		if err := validateBech32(validatorFilter.Operator, prefixes.ValAddr); err != nil {
+			return fmt.Errorf("invalid validator operator %s: %s", validatorFilter.Operator, err)
+		}
+
+		if validatorFilter.PubKey == "" {
+			continue
+		}
+
+		if err := validateBech32(validatorFilter.PubKey, prefixes.ConsPub); err != nil {
+			return fmt.Errorf("invalid validator public key %s: %s", validatorFilter.PubKey, err)
+		}
+	}
+
+	for _, accountFilter := range f.Accounts {
+		if err := validateBech32(accountFilter.Address, prefixes.AccAddr); err != nil {
+			return fmt.Errorf("invalid account %s: %s", accountFilter.Address, err)
+		}
+	}
+
+	return nil
+}
+
+// validateBech32 validates that a given string is a non-empty Bech32 encoding
+// with a given prefix.
+func validateBech32(s, prefix string) error {
+	hrp, bz, err := bech32.DecodeAndConvert(s)
+	if err != nil {
+		return err
+	} else if hrp != prefix {
+		return fmt.Errorf("expected prefix %s, got %s", prefix, hrp)
+	} else if len(bz) == 0 {
+		return errors.New("empty data")
+	}
+
+	return nil
+}
+
+// validateDeposit validates the deposit related governance configuration which
+// is only required when deposit warnings are enabled. The max deposit period is
+// only required by the legacy API as deposit periods otherwise end at a known
+// time.
+func (gov Governance) validateDeposit(legacyAPI bool) error {
+	if gov.DepositWarning == 0 {
+		return nil
+	}
+
+	if gov.MaxDepositPeriod == 0 && legacyAPI {
+		return errors.New("deposit warnings require a max deposit period")
+	}
+
+	coins, err := sdk.ParseCoins(gov.MinDeposit)
+	if err != nil {
+		return err
+	} else if coins.IsZero() {
+		return errors.New("deposit warnings require a minimum deposit")
+	}
+
+	return nil
+}
+
+// MonitorEnabled returns true if a given monitor is enabled either explicitly
+// or by enabling all monitors, in which case a monitor missing its required
+// configuration is not enabled.
+func (cfg Config) MonitorEnabled(monitor string) bool {
+	for _, m := range cfg.Monitors {
+		if m == monitor {
+			return true
+		} else if m == MonitorAll {
+			return cfg.CheckMonitor(monitor) == nil
+		}
+	}
+
+	return false
+}
+
+// validateMonitors validates that every explicitly enabled monitor has its
+// required configuration.
+func (cfg Config) validateMonitors() error {
+	for _, monitor := range cfg.Monitors {
+		if monitor == MonitorAll {
+			continue
+		}
+
+		if err := cfg.CheckMonitor(monitor); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckMonitor returns an error if a given monitor is missing its required
+// configuration.
+func (cfg Config) CheckMonitor(monitor string) error {
+	switch monitor {
+	case MonitorMissingVotes:
+		if (cfg.Governance.VotingPeriod == 0 && cfg.Network.LegacyAPI()) || len(cfg.Governance.VoteReminders) == 0 {
+			return errors.New("missing votes monitor requires a voting period and vote reminders")
+		}
+
+	case MonitorLowUptime:
+		if cfg.Slashing.SignedBlocksWindow == 0 || cfg.Slashing.MinUptime == 0 {
+			return errors.New("low uptime monitor requires a signed blocks window and minimum uptime")
+		}
+
+	case MonitorLowBalances:
+		if len(cfg.Filters.Accounts) == 0 {
+			return errors.New("low balances monitor requires account filters")
+		}
+
+	case MonitorSoftwareUpgrades:
+		if len(cfg.Governance.UpgradeCountdowns) == 0 {
+			return errors.New("software upgrades monitor requires upgrade countdowns")
+		}
+
+	case MonitorUndelegations:
+		if cfg.Staking.MaxUndelegationAmount == 0 && cfg.Staking.MaxUndelegationShare == 0 {
+			return errors.New("undelegations monitor requires a maximum undelegation amount or share")
+		}
+
+	case MonitorLowSelfDelegation:
+		if !cfg.hasMinSelfDelegations() {
+			return errors.New("low self-delegation monitor requires a minimum self-delegation")
+		}
+
+	case MonitorMissingProposals:
+		if cfg.Consensus.ProposalMissFactor == 0 {
+			return errors.New("missing proposals monitor requires a proposal miss factor")
+		}
+		if len(cfg.Network.RPCClients) == 0 {
+			return errors.New("missing proposals monitor requires rpc_clients")
+		}
+
+	case MonitorMempoolSaturation:
+		if cfg.Mempool.MaxTxs == 0 {
+			return errors.New("mempool saturation monitor requires a maximum number of transactions")
+		}
+	}
+
+	return nil
+}
+
+// hasMinSelfDelegations returns true if every filtered validator has a
+// minimum self-delegation either of its own or by default.
+func (cfg Config) hasMinSelfDelegations() bool {
+	if cfg.Staking.MinSelfDelegation != 0 {
+		return true
+	}
+
+	for _, validatorFilter := range cfg.Filters.Validators {
+		if validatorFilter.MinSelfDelegation == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newConfigErr returns an invalid configuration error of the chain with the
+// given ID if any.
+func newConfigErr(chainID string, err error) error {
+	if chainID == "" {
+		return fmt.Errorf("invalid configuration: \"%s\"", err)
+	}
+
+	return fmt.Errorf("invalid configuration of chain %s: \"%s\"", chainID, err)
 }
@@ -1,6 +1,8 @@
 package config_test
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -23,6 +25,12 @@ func newTestValidConfig() config.Config {
 					Address:  "DBA70FA7E9D55E035AD87B41C4DC0C38511FD09A",
 				},
 			},
+			Accounts: []config.AccountFilter{
+				config.AccountFilter{
+					Address:    "cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg",
+					MinBalance: "100steak",
+				},
+			},
 		},
 		Network: config.NetworkConfig{
 			ListenAddr: "0.0.0.0:36655",
@@ -34,9 +42,43 @@ func newTestValidConfig() config.Config {
 				FromName: "Cosmos Titan",
 			},
 		},
+		Governance: config.Governance{
+			VotingPeriod:      20000,
+			VoteReminders:     []uint{72, 24, 2},
+			MaxDepositPeriod:  20000,
+			MinDeposit:        "10steak",
+			DepositWarning:    24,
+			UpgradeCountdowns: []uint{24, 6, 1},
+		},
+		Slashing: config.Slashing{
+			SignedBlocksWindow: 10000,
+			MinUptime:          95,
+		},
+		Staking: config.Staking{
+			MaxUndelegationAmount: 10000,
+			MaxUndelegationShare:  5,
+			MinSelfDelegation:     1000,
+			SelfDelegationMargin:  10,
+		},
+		Consensus: config.Consensus{
+			MaxRound:           3,
+			ProposalMissFactor: 5,
+		},
+		Mempool: config.Mempool{
+			MaxTxs:         5000,
+			MaxBytes:       10000000,
+			SustainedPolls: 3,
+		},
 	}
 }
 
+// setTestGatewayFilters sets the filters of a given configuration to the
+// filters of newTestValidConfig encoded with the default Bech32 prefixes.
+func setTestGatewayFilters(cfg *config.Config) {
+	cfg.Filters.Validators[0].Operator = "cosmosvaloper1chchjxgackcqkn9fqgpsc4n9xamx4flg3l48dx"
+	cfg.Filters.Accounts[0].Address = "cosmos1chchjxgackcqkn9fqgpsc4n9xamx4flg5tpjp4"
+}
+
 func TestValidConfig(t *testing.T) {
 	cfg := newTestValidConfig()
 
@@ -87,4 +129,532 @@ func TestInvalidNetwork(t *testing.T) {
 	cfg.Network.Clients = []string{}
 	err = cfg.Validate()
 	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Network.RPCClients = []string{"http://localhost:26657"}
+	err = cfg.Validate()
+	require.NoError(t, err)
+
+	cfg.Network.RPCClients = []string{"invalid"}
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Network.MaxHeightLag = -1
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Network.Quorum = 1
+	err = cfg.Validate()
+	require.NoError(t, err)
+
+	cfg.Network.Quorum = 2
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+	setTestGatewayFilters(&cfg)
+
+	cfg.Network.API = config.APIGateway
+	err = cfg.Validate()
+	require.NoError(t, err)
+
+	cfg.Network.API = "invalid"
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Network.Clients = nil
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+	require.False(t, cfg.VerifyClients())
+
+	// the chain ID only namespaces the chain
+	cfg.ChainID = "test-chain"
+	require.False(t, cfg.VerifyClients())
+
+	cfg.Network.ExpectedChainID = "test-chain"
+	require.True(t, cfg.VerifyClients())
+
+	cfg = newTestValidConfig()
+
+	cfg.Network.GenesisHash = "E2B0A9CC1B1DDFB8A3FA2B0A6E0D0B0A93A7C5D1E2B0A9CC1B1DDFB8A3FA2B0A"
+	err = cfg.Validate()
+	require.NoError(t, err)
+	require.True(t, cfg.VerifyClients())
+
+	cfg.Network.GenesisHash = "invalid"
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Network.RateLimit = 0.5
+	err = cfg.Validate()
+	require.NoError(t, err)
+
+	cfg.Network.RateLimit = -1
+	err = cfg.Validate()
+	require.Error(t, err)
+}
+
+func TestInvalidHTTP(t *testing.T) {
+	cfg := newTestValidConfig()
+
+	cfg.Network.HTTP.Username = "user"
+	cfg.Network.HTTP.Password = "pass"
+	err := cfg.Validate()
+	require.NoError(t, err)
+
+	cfg.Network.HTTP.BearerToken = "token"
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Network.HTTP.Proxy = "invalid"
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Network.HTTP.CACert = "/does/not/exist.pem"
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	tmpFile, err := ioutil.TempFile("", "titan-cert")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	cfg.Network.HTTP.ClientCert = tmpFile.Name()
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg.Network.HTTP.ClientKey = tmpFile.Name()
+	err = cfg.Validate()
+	require.NoError(t, err)
+}
+
+func TestInvalidGovernance(t *testing.T) {
+	cfg := newTestValidConfig()
+
+	// a monitor missing its configuration is skipped if all monitors are enabled
+	cfg.Governance.VotingPeriod = 0
+	err := cfg.Validate()
+	require.NoError(t, err)
+	require.False(t, cfg.MonitorEnabled(config.MonitorMissingVotes))
+
+	cfg.Monitors = []string{config.MonitorMissingVotes}
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Governance.VoteReminders = []uint{}
+	cfg.Monitors = []string{config.MonitorMissingVotes}
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg.Monitors = []string{"new_proposals"}
+	err = cfg.Validate()
+	require.NoError(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Governance.MinDeposit = "invalid"
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg.Governance.MinDeposit = ""
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg.Governance.DepositWarning = 0
+	err = cfg.Validate()
+	require.NoError(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Governance.UpgradeCountdowns = nil
+	err = cfg.Validate()
+	require.NoError(t, err)
+	require.False(t, cfg.MonitorEnabled(config.MonitorSoftwareUpgrades))
+
+	cfg.Monitors = []string{config.MonitorSoftwareUpgrades}
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+	setTestGatewayFilters(&cfg)
+
+	cfg.Governance.VotingPeriod = 0
+	cfg.Governance.MaxDepositPeriod = 0
+	cfg.Network.API = config.APIGateway
+	err = cfg.Validate()
+	require.NoError(t, err)
+}
+
+func TestValidatorFilters(t *testing.T) {
+	cfg := newTestValidConfig()
+
+	cfg.Filters.Validators[0].Address = ""
+	err := cfg.Validate()
+	require.NoError(t, err)
+
+	cfg.Filters.Validators[0].PubKey = "cosmosvalpub1zcjduepqdgvppnyh2g6vw9g8mhj7ttcttz3uahlmjpsajvfj5dyth8w8p0dswa6ruf"
+	err = cfg.Validate()
+	require.NoError(t, err)
+
+	cfg.Filters.Validators[0].PubKey = "invalid"
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg.Filters.Validators[0].PubKey = "cosmosvalconspub1zcjduepqdgvppnyh2g6vw9g8mhj7ttcttz3uahlmjpsajvfj5dyth8w8p0ds3h3mhc"
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Filters.Validators[0].Operator = "cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzh"
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg.Filters.Validators[0].Operator = "cosmosvaloper1chchjxgackcqkn9fqgpsc4n9xamx4flg3l48dx"
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Filters.Validators[0].Address = "invalid"
+	err = cfg.Validate()
+	require.Error(t, err)
+}
+
+func TestInvalidAccountFilters(t *testing.T) {
+	cfg := newTestValidConfig()
+
+	cfg.Filters.Accounts = nil
+	err := cfg.Validate()
+	require.NoError(t, err)
+	require.False(t, cfg.MonitorEnabled(config.MonitorLowBalances))
+
+	cfg.Monitors = []string{config.MonitorLowBalances}
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Filters.Accounts[0].MinBalance = "invalid"
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg.Filters.Accounts[0].MinBalance = "0steak"
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Filters.Accounts[0].Address = "invalid"
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg.Filters.Accounts[0].Address = "cosmos1chchjxgackcqkn9fqgpsc4n9xamx4flg5tpjp4"
+	err = cfg.Validate()
+	require.Error(t, err)
+}
+
+func TestBech32Prefixes(t *testing.T) {
+	cfg := newTestValidConfig()
+	require.Equal(t, config.LegacyBech32Prefixes, cfg.Network.Bech32Prefixes())
+
+	cfg.Network.Bech32.AccAddr = "osmo"
+	err := cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Network.API = config.APIGateway
+	cfg.Network.Bech32 = config.Bech32{AccAddr: "osmo", ValAddr: "osmovaloper"}
+	cfg.Filters.Validators[0].Operator = "osmovaloper1chchjxgackcqkn9fqgpsc4n9xamx4flgx86pqq"
+	cfg.Filters.Validators[0].PubKey = "osmovalconspub1zcjduepqdgvppnyh2g6vw9g8mhj7ttcttz3uahlmjpsajvfj5dyth8w8p0dsd8kres"
+	cfg.Filters.Accounts[0].Address = "osmo1chchjxgackcqkn9fqgpsc4n9xamx4flgusjzh8"
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg.Network.Bech32.ConsPub = "osmovalconspub"
+	err = cfg.Validate()
+	require.NoError(t, err)
+
+	prefixes := cfg.Network.Bech32Prefixes()
+	require.Equal(t, "osmo", prefixes.AccAddr)
+	require.Equal(t, "osmovaloper", prefixes.ValAddr)
+	require.Equal(t, config.DefaultBech32Prefixes.ConsAddr, prefixes.ConsAddr)
+	require.Equal(t, "osmovalconspub", prefixes.ConsPub)
+
+	cfg.Filters.Validators[0].Operator = "cosmosvaloper1chchjxgackcqkn9fqgpsc4n9xamx4flg3l48dx"
+	err = cfg.Validate()
+	require.Error(t, err)
+}
+
+func TestInvalidSlashing(t *testing.T) {
+	cfg := newTestValidConfig()
+
+	cfg.Slashing.SignedBlocksWindow = 0
+	err := cfg.Validate()
+	require.NoError(t, err)
+	require.False(t, cfg.MonitorEnabled(config.MonitorLowUptime))
+
+	cfg.Monitors = []string{config.MonitorLowUptime}
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Slashing.MinUptime = 0
+	cfg.Monitors = []string{config.MonitorLowUptime}
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg.Slashing.MinUptime = 101
+	err = cfg.Validate()
+	require.Error(t, err)
+}
+
+func TestInvalidStaking(t *testing.T) {
+	cfg := newTestValidConfig()
+
+	cfg.Staking.MaxUndelegationAmount = 0
+	err := cfg.Validate()
+	require.NoError(t, err)
+
+	cfg.Staking.MaxUndelegationShare = 0
+	err = cfg.Validate()
+	require.NoError(t, err)
+	require.False(t, cfg.MonitorEnabled(config.MonitorUndelegations))
+
+	cfg.Monitors = []string{config.MonitorUndelegations}
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Staking.MaxUndelegationShare = 101
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Staking.MaxUndelegationAmount = -1
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Staking.MinSelfDelegation = 0
+	err = cfg.Validate()
+	require.NoError(t, err)
+	require.False(t, cfg.MonitorEnabled(config.MonitorLowSelfDelegation))
+
+	cfg.Monitors = []string{config.MonitorLowSelfDelegation}
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	// every validator has its own minimum self-delegation
+	for i := range cfg.Filters.Validators {
+		cfg.Filters.Validators[i].MinSelfDelegation = 1000
+	}
+
+	err = cfg.Validate()
+	require.NoError(t, err)
+
+	cfg.Filters.Validators[0].MinSelfDelegation = -1
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Staking.SelfDelegationMargin = -1
+	err = cfg.Validate()
+	require.Error(t, err)
+}
+
+func TestInvalidConsensus(t *testing.T) {
+	cfg := newTestValidConfig()
+
+	cfg.Consensus.MaxRound = 0
+	err := cfg.Validate()
+	require.NoError(t, err)
+
+	cfg.Consensus.MaxRound = -1
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Consensus.ProposalMissFactor = 0
+	err = cfg.Validate()
+	require.NoError(t, err)
+	require.False(t, cfg.MonitorEnabled(config.MonitorMissingProposals))
+
+	cfg.Monitors = []string{config.MonitorMissingProposals}
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg.Monitors = []string{"consensus_rounds"}
+	err = cfg.Validate()
+	require.NoError(t, err)
+
+	cfg.Consensus.ProposalMissFactor = -1
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	// proposers are only sampled from the RPC clients
+	cfg = newTestValidConfig()
+	require.False(t, cfg.MonitorEnabled(config.MonitorMissingProposals))
+
+	cfg.Network.RPCClients = []string{"http://localhost:26657"}
+	require.True(t, cfg.MonitorEnabled(config.MonitorMissingProposals))
+
+	cfg.Network.RPCClients = nil
+	cfg.Monitors = []string{config.MonitorMissingProposals}
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg.Network.RPCClients = []string{"http://localhost:26657"}
+	err = cfg.Validate()
+	require.NoError(t, err)
+}
+
+func TestInvalidMempool(t *testing.T) {
+	cfg := newTestValidConfig()
+
+	cfg.Mempool.MaxBytes = 0
+	err := cfg.Validate()
+	require.NoError(t, err)
+
+	// the total size is not reported by all nodes
+	cfg = newTestValidConfig()
+
+	cfg.Mempool.MaxTxs = 0
+	err = cfg.Validate()
+	require.NoError(t, err)
+	require.False(t, cfg.MonitorEnabled(config.MonitorMempoolSaturation))
+
+	cfg.Monitors = []string{config.MonitorMempoolSaturation}
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestValidConfig()
+
+	cfg.Mempool.MaxBytes = -1
+	err = cfg.Validate()
+	require.Error(t, err)
+}
+
+// newTestMultiChainConfig returns a valid configuration of two chains with the
+// chain configuration of newTestValidConfig.
+func newTestMultiChainConfig() config.Config {
+	cfg := newTestValidConfig()
+
+	chain := config.ChainConfig{
+		PollInterval: cfg.PollInterval,
+		Monitors:     cfg.Monitors,
+		Filters:      cfg.Filters,
+		Network:      config.NetworkConfig{Clients: cfg.Network.Clients},
+		Governance:   cfg.Governance,
+		Slashing:     cfg.Slashing,
+		Staking:      cfg.Staking,
+		Consensus:    cfg.Consensus,
+		Mempool:      cfg.Mempool,
+	}
+
+	cfg.Chains = []config.ChainConfig{chain, chain}
+	cfg.Chains[0].ID = "test-chain-1"
+	cfg.Chains[1].ID = "test-chain-2"
+
+	cfg.PollInterval = 0
+	cfg.Monitors = nil
+	cfg.Filters = config.Filters{}
+	cfg.Network = config.NetworkConfig{ListenAddr: cfg.Network.ListenAddr}
+	cfg.Governance = config.Governance{}
+	cfg.Slashing = config.Slashing{}
+	cfg.Staking = config.Staking{}
+	cfg.Consensus = config.Consensus{}
+	cfg.Mempool = config.Mempool{}
+
+	return cfg
+}
+
+func TestChainConfigs(t *testing.T) {
+	cfg := newTestValidConfig()
+	cfg.ChainID = "test-chain"
+
+	err := cfg.Validate()
+	require.NoError(t, err)
+	require.Equal(t, []config.Config{cfg}, cfg.ChainConfigs())
+
+	cfg = newTestMultiChainConfig()
+	cfg.Network.HTTP.Timeout = 10
+
+	err = cfg.Validate()
+	require.NoError(t, err)
+
+	chainCfgs := cfg.ChainConfigs()
+	require.Len(t, chainCfgs, 2)
+
+	for i, chainCfg := range chainCfgs {
+		require.Equal(t, cfg.Chains[i].ID, chainCfg.ChainID)
+		require.Equal(t, cfg.Chains[i].Monitors, chainCfg.Monitors)
+		require.Equal(t, cfg.Chains[i].Network.Clients, chainCfg.Network.Clients)
+		require.Equal(t, cfg.Database, chainCfg.Database)
+		require.Equal(t, cfg.Targets, chainCfg.Targets)
+		require.Equal(t, cfg.Network.ListenAddr, chainCfg.Network.ListenAddr)
+		require.Equal(t, cfg.Network.HTTP, chainCfg.Network.HTTP)
+	}
+}
+
+func TestInvalidChains(t *testing.T) {
+	cfg := newTestValidConfig()
+
+	cfg.ChainID = "test/chain"
+	err := cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestMultiChainConfig()
+
+	cfg.Chains[1].ID = cfg.Chains[0].ID
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg.Chains[1].ID = ""
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestMultiChainConfig()
+
+	cfg.Monitors = []string{"*"}
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestMultiChainConfig()
+
+	cfg.Chains[1].Network.HTTP.Timeout = 10
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	cfg = newTestMultiChainConfig()
+
+	cfg.Chains[1].Network.Clients = nil
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.EqualError(t, err, `invalid configuration of chain test-chain-2: "no LCD clients provided"`)
 }
@@ -4,6 +4,17 @@ package config
 const defaultConfigTemplate = `# This is a TOML config file.
 # For more information, see https://github.com/toml-lang/toml
 
+# Optional chain ID of the monitored chain. Alerts and monitor executions are
+# persisted per chain ID and its REST routes are served under
+# /chains/<chain_id>/.
+#
+# NOTE: Multiple chains are monitored by moving the poll interval, monitors,
+# filters, the [network] clients and the monitor sections (governance, slashing,
+# staking, consensus and mempool) into a [[chains]] block per chain with a
+# unique id (see the end of this file). The database, targets, integrations,
+# listen address and [network.http] settings are shared by all chains.
+chain_id = ""
+
 # Poll interval in seconds
 poll_interval = 15
 
@@ -16,6 +27,20 @@ monitors = [
   "jailed_validators",
   "double_signing",
   "missing_signatures",
+  "missing_votes",
+  "proposal_outcomes",
+  "software_upgrades",
+  "low_balances",
+  "low_uptime",
+  "consensus_key_changes",
+  "undelegations",
+  "low_self_delegation",
+  "network_double_signing",
+  "consensus_rounds",
+  "missing_proposals",
+  "mempool_saturation",
+  "client_disagreements",
+  "client_chain_mismatches",
 ]
 
 # Data directory used for the embedded database
@@ -24,11 +49,98 @@ data_dir = "/Users/aleksbez/.titan/data"
 
 # Network configuration including a list of trusted LCD endpoints
 [network]
+# Address of the JSON REST service which also serves Prometheus metrics under
+# /metrics
 listen_addr = "0.0.0.0:36655"
 
 # NOTE: These will be used in a round-robin fashion
 clients = ["https://gaia-seeds.interblock.io:1317"]
 
+# LCD API exposed by the clients. The "legacy" API (default) is the LCD of
+# Cosmos SDK v0.24. The "gateway" API is the gRPC-gateway REST API of current
+# Cosmos SDK versions (e.g. /cosmos/staking/v1beta1/validators). With the
+# gateway API, deposit and voting periods end at the times reported by the
+# proposals, so voting_period and max_deposit_period are not required, and the
+# undelegations monitor always searches transactions via the clients as
+# transaction events of the rpc_clients cannot be decoded.
+api = "legacy"
+
+# Health check interval in seconds for the LCD clients. A client that is
+# unreachable or whose latest height lags behind the highest client by more
+# than the maximum height lag is ejected until a later health check succeeds.
+# A failed request is retried against the next healthy client. A value of zero
+# disables the respective check.
+health_check_interval = 30
+max_height_lag = 10
+
+# Number of LCD clients that must agree on blocks at a height and the validator
+# set. If greater than one, these are read from all healthy clients and only the
+# majority response is used. Clients responding differently than the majority
+# are alerted on by the client_disagreements monitor. A value of zero or one
+# disables quorum reads.
+quorum = 0
+
+# Optional chain ID and HEX encoded hash of the block at height one of the
+# monitored chain. If either is given, every LCD and RPC client is verified to
+# serve that chain at startup and then every verify_interval seconds (0
+# disables periodic verification). Clients are excluded until they are first
+# verified, where unreachable clients are retried even if periodic
+# verification is disabled. Clients serving a different chain are excluded
+# until a later verification succeeds and are alerted on by the
+# client_chain_mismatches monitor. Unlike chain_id, the expected chain ID does
+# not affect where alerts and monitor executions are persisted.
+expected_chain_id = ""
+genesis_hash = ""
+verify_interval = 300
+
+# Maximum number of requests per second to each LCD client and the number of
+# requests that may be sent at once (defaults to one). Requests exceeding the
+# rate are delayed. A client responding with too many requests (HTTP 429) is
+# not used for as long as its Retry-After header asks for (at most a minute)
+# if other clients are available. A rate limit of zero disables rate limiting.
+rate_limit = 0.0
+rate_burst = 1
+
+# Optional Tendermint RPC endpoints to subscribe to new block and transaction
+# events over a websocket instead of polling for the latest block. If the
+# websocket is down, the next endpoint is tried with an exponential backoff and
+# monitors fall back to polling the LCD clients in the meantime.
+rpc_clients = ["http://localhost:26657"]
+
+# HTTP client settings used for all LCD and RPC requests
+#
+# Every request attempt times out after the given number of seconds (0 defaults
+# to 30). Requests failing to connect or receiving a 502, 503 or 504 response
+# are retried up to the given number of times, waiting retry_backoff
+# milliseconds longer before every retry. A custom CA bundle and a client
+# certificate and key for mutual TLS may be given as PEM files. Basic
+# (username/password) and bearer token authentication are mutually exclusive.
+# If no proxy is given, the HTTP_PROXY and HTTPS_PROXY environment variables are
+# honored.
+[network.http]
+timeout = 30
+retries = 2
+retry_backoff = 500
+ca_cert = ""
+client_cert = ""
+client_key = ""
+username = ""
+password = ""
+bearer_token = ""
+proxy = ""
+
+# Bech32 prefixes of the network's account, validator operator and consensus
+# addresses and consensus public keys. Empty prefixes default to those of the
+# configured API, i.e. cosmosaccaddr (operators included), cosmosvaladdr and
+# cosmosvalpub for the legacy API and cosmos, cosmosvaloper, cosmosvalcons and
+# cosmosvalconspub otherwise. Custom prefixes (e.g. osmo and osmovaloper) are
+# not supported by the legacy API.
+[network.bech32]
+acc_addr = ""
+val_addr = ""
+cons_addr = ""
+cons_pub = ""
+
 # List of alerting targets
 #
 # NOTE: Webhooks are currently not supported and SMS and email targets are
@@ -38,15 +150,110 @@ webhooks = []
 sms_recipients = ["+11234567890"]
 email_recipients = ["foo@bar.com"]
 
-# A list of validator filters to filter against when executing monitors
+# A list of validator and account filters to filter against when executing
+# monitors
 #
-# Note a validator operator must have a valid Bech32 prefix and the address must
-# be a valid HEX address
+# Note a validator operator must be a valid Bech32 address with the network's
+# validator prefix. The consensus address is optional and must be a valid HEX
+# address if given. If omitted, it is derived at startup from the optional
+# Bech32 consensus public key (pubkey) or otherwise looked up from the
+# validator's operator. An account must be a valid Bech32 address with the
+# network's account prefix and a minimum balance for each denomination to
+# monitor.
 [filters]
   [filters.validator]
     operator = "cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg"
     address = "DBA70FA7E9D55E035AD87B41C4DC0C38511FD09A"
 
+  [filters.account]
+    address = "cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg"
+    min_balance = "100steak"
+
+# Governance monitoring configuration
+#
+# NOTE: The voting and deposit periods are in blocks and must match the
+# network's governance parameters along with the minimum deposit. Vote reminders
+# and the deposit warning are in hours before the respective period ends and are
+# estimated from the average block time. A deposit warning of 0 disables
+# warnings for proposals that are about to fail their deposit period.
+#
+# Upgrade countdowns are in hours before a software upgrade proposal's planned
+# height is reached. The height (and optional name) is extracted from the
+# proposal's description, e.g. "height: 1000000" and "name: v0.25.0".
+[governance]
+voting_period = 20000
+vote_reminders = [72, 24, 2]
+max_deposit_period = 20000
+min_deposit = "10steak"
+deposit_warning = 24
+upgrade_countdowns = [24, 6, 1]
+
+# Slashing monitoring configuration
+#
+# NOTE: The signed blocks window must match the network's slashing parameters.
+# The minimum uptime is a percentage of blocks signed within the window.
+#
+# Network-wide double signing alerts report evidence against any validator and
+# are prefixed with their severity (info, warning or critical).
+[slashing]
+signed_blocks_window = 10000
+min_uptime = 95.0
+network_double_sign_severity = "critical"
+
+# Staking monitoring configuration
+#
+# NOTE: When the consensus key of a filtered validator changes, an alert is
+# always triggered. If the consensus key is followed, all monitors will use the
+# validator's new consensus address until Titan is restarted.
+#
+# An unbonding delegation or redelegation from a filtered validator triggers an
+# alert when it exceeds the maximum amount of tokens or the maximum percentage
+# of the validator's tokens (0-100). A value of zero disables the threshold.
+#
+# A filtered validator's self-delegation (in tokens) triggers an alert when it
+# falls below the minimum self-delegation plus a safety margin (as a
+# percentage of the minimum).
+[staking]
+follow_consensus_key = false
+max_undelegation_amount = 10000
+max_undelegation_share = 5.0
+min_self_delegation = 1000
+self_delegation_margin = 10.0
+
+# Consensus monitoring configuration
+#
+# NOTE: The consensus state is queried from the Tendermint RPC endpoints, so
+# the consensus_rounds and missing_proposals monitors require rpc_clients to be
+# set. A filtered validator's missing prevotes and precommits are alerted on
+# once the round has progressed past the respective step. An alert is also
+# triggered when the network reaches the maximum round at a height. A zero
+# value disables the check.
+#
+# Block headers do not contain their proposer, so the proposer of the current
+# round is sampled once per poll and the sample is counted once its block is
+# committed in that round. Samples, not blocks, are counted. A validator
+# proposes a counted sample with a probability of its voting power share, so an
+# alert is triggered when a filtered validator has not proposed any of the
+# counted samples for longer than the proposal miss factor divided by its
+# share.
+[consensus]
+max_round = 3
+proposal_miss_factor = 5.0
+
+# Mempool monitoring configuration
+#
+# NOTE: The mempool of every node in rpc_clients is checked at each poll. An
+# alert is triggered when a node's number of unconfirmed transactions or their
+# total size in bytes exceeds the respective maximum for the given number of
+# consecutive polls. The maximum number of transactions is required while a
+# maximum size of zero disables its threshold. Nodes that do not report the total
+# size of their mempool (e.g. Tendermint v0.23) are only checked against the
+# maximum number of transactions.
+[mempool]
+max_txs = 5000
+max_bytes = 10000000
+sustained_polls = 3
+
 # A list of API integration configurations
 #
 # NOTE: Only SendGrid is supported at the moment
@@ -54,4 +261,18 @@ email_recipients = ["foo@bar.com"]
   [integrations.sendgrid]
     api_key = ""
     from_name = "Cosmos Titan"
+
+# Example of a chain monitored along with other chains
+#
+# [[chains]]
+# id = "cosmoshub-4"
+# poll_interval = 15
+# monitors = ["jailed_validators", "missing_signatures"]
+#
+#   [chains.network]
+#   clients = ["https://lcd.cosmos.example.com"]
+#   api = "gateway"
+#
+#   [[chains.filters.validator]]
+#   operator = "cosmosvaloper1chchjxgackcqkn9fqgpsc4n9xamx4flg3l48dx"
 `
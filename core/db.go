@@ -179,6 +179,17 @@ func (bdb *BadgerDB) runGC() {
 	}
 }
 
+// ChainNamespace returns a namespace scoped to a given chain ID so that the
+// persisted data of multiple monitored chains do not collide. An empty chain ID
+// returns the namespace itself.
+func ChainNamespace(chainID string, namespace []byte) []byte {
+	if chainID == "" {
+		return namespace
+	}
+
+	return []byte(fmt.Sprintf("%s/%s", chainID, namespace))
+}
+
 // badgerNamespaceKey returns a composite key used for lookup and storage for a
 // given namespace and key.
 func badgerNamespaceKey(namespace, key []byte) []byte {
@@ -0,0 +1,272 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/alexanderbez/titan/config"
+)
+
+// HTTP client related defaults.
+const (
+	defaultRequestTimeout = 30 * time.Second
+	defaultRetryBackoff   = 500 * time.Millisecond
+)
+
+var (
+	defaultHTTPClientMu sync.RWMutex
+	defaultHTTPClient   = newDefaultHTTPClient()
+)
+
+// HTTPClient implements an HTTP client with per-request timeouts, retries of
+// failed requests, custom TLS configuration, authentication and proxies. All
+// in-flight requests are cancelled once the client is closed.
+type HTTPClient struct {
+	client    *http.Client
+	transport *http.Transport
+	header    http.Header
+
+	timeout time.Duration
+	retries uint
+	backoff time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewHTTPClient returns a reference to a new HTTPClient from the given HTTP
+// configuration. An error is returned if the CA bundle, client certificate or
+// proxy is invalid.
+func NewHTTPClient(cfg config.HTTP) (*HTTPClient, error) {
+	tlsConfig := &tls.Config{}
+
+	if cfg.CACert != "" {
+		rawCA, err := ioutil.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read CA bundle")
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(rawCA) {
+			return nil, errors.New("failed to parse CA bundle")
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load client certificate")
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid proxy")
+		}
+
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	header := make(http.Header)
+	if cfg.BearerToken != "" {
+		header.Set("Authorization", "Bearer "+cfg.BearerToken)
+	} else if cfg.Username != "" {
+		req := &http.Request{Header: make(http.Header)}
+		req.SetBasicAuth(cfg.Username, cfg.Password)
+
+		header.Set("Authorization", req.Header.Get("Authorization"))
+	}
+
+	hc := newHTTPClient(proxy, tlsConfig, header)
+	hc.retries = cfg.Retries
+
+	if cfg.Timeout != 0 {
+		hc.timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	if cfg.RetryBackoff != 0 {
+		hc.backoff = time.Duration(cfg.RetryBackoff) * time.Millisecond
+	}
+
+	return hc, nil
+}
+
+func newHTTPClient(proxy func(*http.Request) (*url.URL, error), tlsConfig *tls.Config, header http.Header) *HTTPClient {
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       tlsConfig,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &HTTPClient{
+		client:    &http.Client{Transport: transport},
+		transport: transport,
+		header:    header,
+		timeout:   defaultRequestTimeout,
+		backoff:   defaultRetryBackoff,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+func newDefaultHTTPClient() *HTTPClient {
+	return newHTTPClient(http.ProxyFromEnvironment, &tls.Config{}, make(http.Header))
+}
+
+// DefaultHTTPClient returns the HTTP client used by Request.
+func DefaultHTTPClient() *HTTPClient {
+	defaultHTTPClientMu.RLock()
+	defer defaultHTTPClientMu.RUnlock()
+
+	return defaultHTTPClient
+}
+
+// SetDefaultHTTPClient sets the HTTP client used by Request.
+func SetDefaultHTTPClient(hc *HTTPClient) {
+	defaultHTTPClientMu.Lock()
+	defer defaultHTTPClientMu.Unlock()
+
+	defaultHTTPClient = hc
+}
+
+// Do invokes a request of type method to the given url with an optional
+// payload. Each attempt is bound by the client's timeout and the given context.
+// Requests failing to connect or receiving a bad gateway, service unavailable
+// or gateway timeout status are retried with a linear backoff. A too many
+// requests status is not retried and results in a ThrottledError with the
+// response's Retry-After. The raw response body, the status code and any error
+// will be returned.
+func (hc *HTTPClient) Do(ctx context.Context, url, method string, payload []byte) ([]byte, int, error) {
+	var (
+		body   []byte
+		status int
+		err    error
+	)
+
+	for attempt := uint(0); attempt <= hc.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, 0, ctx.Err()
+
+			case <-hc.ctx.Done():
+				return nil, 0, errors.New("http client closed")
+
+			case <-time.After(time.Duration(attempt) * hc.backoff):
+			}
+		}
+
+		body, status, err = hc.do(ctx, url, method, payload)
+		if _, ok := err.(*ThrottledError); ok {
+			return nil, status, err
+		}
+
+		if err == nil && !retryableStatus(status) {
+			return body, status, nil
+		}
+
+		if ctx.Err() != nil || hc.ctx.Err() != nil {
+			break
+		}
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return body, status, nil
+}
+
+// Transport returns the client's underlying transport, e.g. to reuse its proxy
+// and TLS configuration.
+func (hc *HTTPClient) Transport() *http.Transport { return hc.transport }
+
+// Header returns a copy of the headers set on every request, e.g. the
+// authorization header.
+func (hc *HTTPClient) Header() http.Header {
+	header := make(http.Header)
+	for k, v := range hc.header {
+		header[k] = append([]string(nil), v...)
+	}
+
+	return header
+}
+
+// Close cancels all in-flight requests of the client.
+func (hc *HTTPClient) Close() { hc.cancel() }
+
+func (hc *HTTPClient) do(ctx context.Context, url, method string, payload []byte) ([]byte, int, error) {
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for k, v := range hc.header {
+		req.Header[k] = v
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, hc.timeout)
+	defer cancel()
+
+	// cancel the request if the client is closed
+	go func() {
+		select {
+		case <-hc.ctx.Done():
+			cancel()
+
+		case <-reqCtx.Done():
+		}
+	}()
+
+	res, err := hc.client.Do(req.WithContext(reqCtx))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+
+	rawBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		return nil, res.StatusCode, &ThrottledError{RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"))}
+	}
+
+	return rawBody, res.StatusCode, nil
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+
+	default:
+		return false
+	}
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsNamespace defines the namespace of all exported Prometheus metrics.
+const metricsNamespace = "titan"
+
+var defaultMetrics = NewMetrics()
+
+// Metrics defines the Prometheus metrics exported by Titan. Metrics of a chain
+// are labeled with its chain ID, which is empty when monitoring a single chain
+// without one, and metrics of a validator with its operator address.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	mu            sync.Mutex
+	latestHeights map[string]int64
+
+	// PollDuration observes the duration of every poll of all monitors.
+	PollDuration *prometheus.HistogramVec
+	// MonitorExecutions counts monitor executions by result (success or
+	// failure). A failed execution includes one with nothing to alert on.
+	MonitorExecutions *prometheus.CounterVec
+	// AlertsSent and AlertsFailed count the alerts sent by each alerter.
+	AlertsSent   *prometheus.CounterVec
+	AlertsFailed *prometheus.CounterVec
+
+	// ClientRequestDuration observes the latency of every request to a client.
+	ClientRequestDuration *prometheus.HistogramVec
+	// ClientRequestsThrottled counts the requests a client rejected with too
+	// many requests and ClientRequestsDelayed the requests delayed by a client's
+	// rate limit or Retry-After.
+	ClientRequestsThrottled *prometheus.CounterVec
+	ClientRequestsDelayed   *prometheus.CounterVec
+
+	// LatestBlockHeight is the height of the latest block observed. It should
+	// be set through ObserveLatestHeight only.
+	LatestBlockHeight *prometheus.GaugeVec
+
+	// ValidatorMissedBlocks is the number of blocks a validator missed in the
+	// signed blocks window, ValidatorJailed is one if a validator is jailed and
+	// zero otherwise and ValidatorVotingPower is a validator's bonded tokens.
+	ValidatorMissedBlocks *prometheus.GaugeVec
+	ValidatorJailed       *prometheus.GaugeVec
+	ValidatorVotingPower  *prometheus.GaugeVec
+}
+
+// NewMetrics returns a reference to a new Metrics with all metrics registered
+// in a new registry along with the Go runtime metrics.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry:      prometheus.NewRegistry(),
+		latestHeights: make(map[string]int64),
+
+		PollDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "manager",
+			Name:      "poll_duration_seconds",
+			Help:      "Duration of polling all monitors of a chain.",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 10),
+		}, []string{"chain_id"}),
+		MonitorExecutions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "manager",
+			Name:      "monitor_executions_total",
+			Help:      "Number of monitor executions by result.",
+		}, []string{"chain_id", "monitor", "result"}),
+		AlertsSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "manager",
+			Name:      "alerts_sent_total",
+			Help:      "Number of alerts sent by an alerter.",
+		}, []string{"chain_id", "alerter"}),
+		AlertsFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "manager",
+			Name:      "alerts_failed_total",
+			Help:      "Number of alerts an alerter failed to send.",
+		}, []string{"chain_id", "alerter"}),
+
+		ClientRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "client",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of requests to a client.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"client"}),
+		ClientRequestsThrottled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "client",
+			Name:      "requests_throttled_total",
+			Help:      "Number of requests a client rejected with too many requests.",
+		}, []string{"client"}),
+		ClientRequestsDelayed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "client",
+			Name:      "requests_delayed_total",
+			Help:      "Number of requests delayed by a client's rate limit or Retry-After.",
+		}, []string{"client"}),
+
+		LatestBlockHeight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "chain",
+			Name:      "latest_block_height",
+			Help:      "Height of the latest observed block.",
+		}, []string{"chain_id"}),
+
+		ValidatorMissedBlocks: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "validator",
+			Name:      "missed_blocks",
+			Help:      "Number of blocks missed by a validator in the signed blocks window.",
+		}, []string{"chain_id", "validator"}),
+		ValidatorJailed: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "validator",
+			Name:      "jailed",
+			Help:      "Whether a validator is jailed (1) or not (0).",
+		}, []string{"chain_id", "validator"}),
+		ValidatorVotingPower: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "validator",
+			Name:      "voting_power",
+			Help:      "Bonded tokens of a validator.",
+		}, []string{"chain_id", "validator"}),
+	}
+
+	m.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		m.PollDuration,
+		m.MonitorExecutions,
+		m.AlertsSent,
+		m.AlertsFailed,
+		m.ClientRequestDuration,
+		m.ClientRequestsThrottled,
+		m.ClientRequestsDelayed,
+		m.LatestBlockHeight,
+		m.ValidatorMissedBlocks,
+		m.ValidatorJailed,
+		m.ValidatorVotingPower,
+	)
+
+	return m
+}
+
+// ObserveLatestHeight records the height of a latest block of a given chain
+// unless a greater height has been observed already, so that a client that is
+// behind or a late event never moves the observed height backwards.
+func (m *Metrics) ObserveLatestHeight(chainID string, height int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if height <= m.latestHeights[chainID] {
+		return
+	}
+
+	m.latestHeights[chainID] = height
+	m.LatestBlockHeight.WithLabelValues(chainID).Set(float64(height))
+}
+
+// DefaultMetrics returns the metrics all components record to.
+func DefaultMetrics() *Metrics { return defaultMetrics }
+
+// Handler returns an HTTP handler exposing the metrics in the Prometheus text
+// format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
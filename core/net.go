@@ -1,60 +1,714 @@
 package core
 
 import (
-	"bytes"
-	"io/ioutil"
+	"context"
+	"fmt"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 )
 
 const (
 	// RequestGET defines a GET HTTP request method.
 	RequestGET = "GET"
+
+	// maxDisagreements defines the maximum number of recorded disagreements
+	// kept until they are retrieved.
+	maxDisagreements = 100
+
+	// unverifiedRetryInterval defines the interval at which clients that have
+	// not been verified yet are verified again if periodic verification is
+	// disabled.
+	unverifiedRetryInterval = 30 * time.Second
 )
 
 // Request implements a generic HTTP request handler. It will invoke a request
-// of type method to the given url with an optional payload. The raw response
-// body and any error will be returned.
+// of type method to the given url with an optional payload using the default
+// HTTP client. The raw response body and any error will be returned.
 func Request(url, method string, payload []byte) ([]byte, error) {
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(payload))
-	if err != nil {
-		return nil, err
-	}
+	return RequestWithContext(context.Background(), url, method, payload)
+}
 
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
+// RequestWithContext implements Request where the request is cancelled once
+// the given context is done.
+func RequestWithContext(ctx context.Context, url, method string, payload []byte) ([]byte, error) {
+	rawBody, _, err := DefaultHTTPClient().Do(ctx, url, method, payload)
+	return rawBody, err
+}
 
-	rawBody, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return nil, err
-	}
+// request implements Request returning the response status code as well.
+func request(url, method string, payload []byte) ([]byte, int, error) {
+	return DefaultHTTPClient().Do(context.Background(), url, method, payload)
+}
+
+// DigestFunc defines a function that returns a digest of a response body used
+// to compare the responses of different clients in a quorum read.
+type DigestFunc func(body []byte) (string, error)
 
-	res.Body.Close()
-	return rawBody, nil
+// Disagreement defines a quorum read where one or more clients responded
+// differently than the majority of clients.
+type Disagreement struct {
+	Path       string   `json:"path"`
+	Majority   []string `json:"majority"`
+	Dissenting []string `json:"dissenting"`
+}
+
+// StatusError defines an error of a request that received an unsuccessful
+// (i.e. non-2xx) status.
+type StatusError struct {
+	Status int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("received status %d", e.Status)
+}
+
+// IsNotFound returns true if the given error is a StatusError of a not found
+// status.
+func IsNotFound(err error) bool {
+	statusErr, ok := err.(*StatusError)
+	return ok && statusErr.Status == http.StatusNotFound
+}
+
+// HealthCheckFunc defines a function that returns the latest height of a given
+// client or an error if the client is unreachable.
+type HealthCheckFunc func(client string) (int64, error)
+
+// VerifyFunc defines a function that verifies a given client serves the
+// expected chain. It returns the reason of a mismatch, which is empty if the
+// client matches, or an error if the client could not be verified.
+type VerifyFunc func(client string) (mismatch string, err error)
+
+// UnverifiedReason defines the exclusion reason of clients that have not been
+// verified yet.
+const UnverifiedReason = "unverified"
+
+// Exclusion defines a client excluded from use along with the reason.
+type Exclusion struct {
+	Client string `json:"client"`
+	Reason string `json:"reason"`
 }
 
 // ClientManager implements a simple round-robin load balancing client manager.
+// Clients may be actively health checked in which case unhealthy clients are
+// ejected from the rotation until a later health check succeeds. If no client
+// is healthy, all clients are used. Clients may also be verified to serve the
+// expected chain in which case clients are excluded and never used until a
+// verification succeeds, i.e. clients are excluded from the start until they
+// are verified and mismatching clients until a later verification succeeds.
+//
+// Requests through the manager are rate limited per client by a token bucket
+// if a rate limit is set. A client responding with too many requests is
+// throttled for its Retry-After, during which requests to it are delayed and
+// it is skipped in the rotation if other clients are available.
 type ClientManager struct {
-	mu      sync.Mutex
-	index   int
-	clients []string
+	mu        sync.Mutex
+	index     int
+	clients   []string
+	unhealthy map[string]bool
+	excluded  map[string]string
+	limiters  map[string]*tokenBucket
+
+	quorum        int
+	disagreements []Disagreement
+
+	quit chan struct{}
+	wg   sync.WaitGroup
 }
 
 // NewClientManager returns a reference to a new initialized ClientManager with
 // a given list of clients.
 func NewClientManager(clients []string) *ClientManager {
-	return &ClientManager{clients: clients}
+	cm := &ClientManager{
+		clients:   clients,
+		unhealthy: make(map[string]bool),
+		excluded:  make(map[string]string),
+		limiters:  make(map[string]*tokenBucket),
+	}
+
+	for _, client := range clients {
+		cm.limiters[client] = newTokenBucket(0, 0)
+	}
+
+	return cm
+}
+
+// SetRateLimit limits the requests to every client to the given rate per
+// second with the given burst. A zero rate disables rate limiting.
+func (cm *ClientManager) SetRateLimit(rate float64, burst int) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	for _, client := range cm.clients {
+		cm.limiters[client] = newTokenBucket(rate, burst)
+	}
 }
 
-// Next returns the next client to be used from the client manager. Each client
-// is round-robin load balanced.
+// Next returns the next healthy client to be used from the client manager.
+// Each healthy client is round-robin load balanced and throttled clients are
+// skipped. An empty client is returned if every client is excluded.
 func (cm *ClientManager) Next() string {
 	cm.mu.Lock()
-	client := cm.clients[cm.index]
-	cm.index = (cm.index + 1) % len(cm.clients)
+	defer cm.mu.Unlock()
+
+	// fall back to plain round-robin of the clients that are not excluded if
+	// every client is unhealthy or throttled
+	for _, skipUnhealthy := range []bool{true, false} {
+		for i := 0; i < len(cm.clients); i++ {
+			client := cm.clients[cm.index]
+			cm.index = (cm.index + 1) % len(cm.clients)
+
+			if _, ok := cm.excluded[client]; ok {
+				continue
+			}
+
+			if skipUnhealthy && (cm.unhealthy[client] || cm.limiters[client].throttled()) {
+				continue
+			}
+
+			return client
+		}
+	}
+
+	return ""
+}
+
+// Healthy returns all the clients that are currently healthy and not excluded.
+func (cm *ClientManager) Healthy() []string {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	var healthy []string
+	for _, client := range cm.clients {
+		if _, ok := cm.excluded[client]; !ok && !cm.unhealthy[client] {
+			healthy = append(healthy, client)
+		}
+	}
+
+	return healthy
+}
+
+// Exclusions returns all the clients that are currently excluded in the order
+// of the clients.
+func (cm *ClientManager) Exclusions() []Exclusion {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	var exclusions []Exclusion
+	for _, client := range cm.clients {
+		if reason, ok := cm.excluded[client]; ok {
+			exclusions = append(exclusions, Exclusion{Client: client, Reason: reason})
+		}
+	}
+
+	return exclusions
+}
+
+// Included returns all the clients that are not excluded regardless of their
+// health.
+func (cm *ClientManager) Included() []string {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	var clients []string
+	for _, client := range cm.clients {
+		if _, ok := cm.excluded[client]; !ok {
+			clients = append(clients, client)
+		}
+	}
+
+	return clients
+}
+
+// Request invokes a request to the given url similar to Request. If the url
+// belongs to one of the manager's clients, the request is rate limited and if
+// it fails (i.e. the client is unreachable, throttled or responds with a
+// server error status), it is retried against the remaining healthy clients
+// in order until one does not fail. The last error is returned if all clients
+// fail. Any unsuccessful status results in a StatusError.
+func (cm *ClientManager) Request(url, method string, payload []byte) ([]byte, error) {
+	resp, err := cm.RequestClient(url, method, payload)
+	if !shouldFailover(err) {
+		return resp, err
+	}
+
+	path := strings.TrimPrefix(url, cm.clientOf(url))
+
+	for _, client := range cm.failover(url) {
+		resp, retryErr := cm.RequestClient(client+path, method, payload)
+		if !shouldFailover(retryErr) {
+			return resp, retryErr
+		}
+
+		err = retryErr
+	}
+
+	return nil, err
+}
+
+// RequestClient invokes a request to the given url similar to Request without
+// failing over to other clients, e.g. to health check or verify the client the
+// url belongs to.
+func (cm *ClientManager) RequestClient(url, method string, payload []byte) ([]byte, error) {
+	body, status, err := cm.request(url, method, payload)
+	if err == nil && (status < http.StatusOK || status >= http.StatusMultipleChoices) {
+		err = &StatusError{Status: status}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// shouldFailover returns true if a request failed with an error another client
+// may not fail with, i.e. any error other than a StatusError of a client error
+// status.
+func shouldFailover(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if statusErr, ok := err.(*StatusError); ok {
+		return statusErr.Status >= http.StatusInternalServerError
+	}
+
+	return true
+}
+
+// request invokes a request to the given url similar to request. If the url
+// belongs to one of the manager's clients, the request waits on the client's
+// rate limit and Retry-After first and the client is throttled if it responds
+// with too many requests. The request's latency is recorded as a metric.
+func (cm *ClientManager) request(url, method string, payload []byte) ([]byte, int, error) {
+	client := cm.clientOf(url)
+	if client == "" {
+		return request(url, method, payload)
+	}
+
+	cm.mu.Lock()
+	limiter := cm.limiters[client]
 	cm.mu.Unlock()
 
-	return client
+	wait := limiter.reserve()
+
+	metrics := DefaultMetrics()
+
+	if wait > 0 {
+		metrics.ClientRequestsDelayed.WithLabelValues(client).Inc()
+		time.Sleep(wait)
+	}
+
+	start := time.Now()
+	body, status, err := request(url, method, payload)
+	metrics.ClientRequestDuration.WithLabelValues(client).Observe(time.Since(start).Seconds())
+
+	if throttledErr, ok := err.(*ThrottledError); ok {
+		limiter.throttle(throttledErr.RetryAfter)
+		metrics.ClientRequestsThrottled.WithLabelValues(client).Inc()
+	}
+
+	return body, status, err
+}
+
+// SetQuorum sets the number of clients that must agree on the response of a
+// quorum read. A quorum of one or less disables quorum reads.
+func (cm *ClientManager) SetQuorum(quorum int) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.quorum = quorum
+}
+
+// Quorum returns the number of clients that must agree on the response of a
+// quorum read.
+func (cm *ClientManager) Quorum() int {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	return cm.quorum
+}
+
+// QuorumRequest invokes a GET request to the given url against all healthy
+// clients if a quorum is set and returns the response of the majority of
+// clients. Responses are compared by the given digest or by their body if it
+// is nil. Any client responding differently than the majority is recorded as
+// a disagreement. An error is returned if fewer clients than the quorum agree
+// or there is no single majority. Without a quorum, the request is invoked
+// similar to Request.
+func (cm *ClientManager) QuorumRequest(url string, digest DigestFunc) ([]byte, error) {
+	quorum := cm.Quorum()
+	if quorum <= 1 {
+		return cm.Request(url, RequestGET, nil)
+	}
+
+	path := strings.TrimPrefix(url, cm.clientOf(url))
+
+	clients := cm.Healthy()
+	if len(clients) < quorum {
+		clients = cm.Included()
+	}
+
+	type response struct {
+		body   []byte
+		digest string
+		err    error
+	}
+
+	responses := make([]response, len(clients))
+
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+
+		go func(i int, client string) {
+			defer wg.Done()
+
+			// a client that cannot serve the request (e.g. it is behind) does not
+			// count towards any response
+			body, err := cm.RequestClient(client+path, RequestGET, nil)
+			if err != nil {
+				responses[i] = response{err: err}
+				return
+			}
+
+			d := string(body)
+			if digest != nil {
+				d, err = digest(body)
+			}
+
+			responses[i] = response{body: body, digest: d, err: err}
+		}(i, client)
+	}
+
+	wg.Wait()
+
+	// group clients by their response in the order of the clients
+	var digests []string
+	groups := make(map[string][]int)
+
+	for i, resp := range responses {
+		if resp.err != nil {
+			continue
+		}
+
+		if _, ok := groups[resp.digest]; !ok {
+			digests = append(digests, resp.digest)
+		}
+
+		groups[resp.digest] = append(groups[resp.digest], i)
+	}
+
+	if len(digests) == 0 {
+		return nil, fmt.Errorf("no client responded to %s", path)
+	}
+
+	majority, tie := digests[0], false
+	for _, d := range digests[1:] {
+		if len(groups[d]) > len(groups[majority]) {
+			majority, tie = d, false
+		} else if len(groups[d]) == len(groups[majority]) {
+			tie = true
+		}
+	}
+
+	if len(digests) > 1 {
+		disagreement := Disagreement{Path: path}
+
+		for _, d := range digests {
+			for _, i := range groups[d] {
+				if d == majority && !tie {
+					disagreement.Majority = append(disagreement.Majority, clients[i])
+				} else {
+					disagreement.Dissenting = append(disagreement.Dissenting, clients[i])
+				}
+			}
+		}
+
+		cm.mu.Lock()
+		cm.disagreements = append(cm.disagreements, disagreement)
+		if len(cm.disagreements) > maxDisagreements {
+			cm.disagreements = cm.disagreements[len(cm.disagreements)-maxDisagreements:]
+		}
+		cm.mu.Unlock()
+	}
+
+	if tie {
+		return nil, fmt.Errorf("clients disagree on %s without a majority", path)
+	}
+
+	if n := len(groups[majority]); n < quorum {
+		return nil, fmt.Errorf("only %d of %d required clients agree on %s", n, quorum, path)
+	}
+
+	return responses[groups[majority][0]].body, nil
+}
+
+// Disagreements returns and clears all the disagreements recorded by quorum
+// reads since the last call.
+func (cm *ClientManager) Disagreements() []Disagreement {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	disagreements := cm.disagreements
+	cm.disagreements = nil
+
+	return disagreements
+}
+
+// StartHealthChecks starts health checking all clients in a go-routine every
+// given interval. A client is unhealthy if it is unreachable or if its latest
+// height lags behind the highest height of all clients by more than the given
+// maximum lag. A zero maximum lag disables the latter check.
+func (cm *ClientManager) StartHealthChecks(check HealthCheckFunc, interval time.Duration, maxLag int64, logger Logger) {
+	cm.run(interval, true, func() { cm.CheckHealth(check, maxLag, logger) })
+}
+
+// StartVerification excludes all clients as unverified, verifies them once and
+// then again in a go-routine every given interval. A zero interval disables the
+// latter, in which case only clients that could not be verified yet (e.g. they
+// were unreachable) are verified again until they are.
+func (cm *ClientManager) StartVerification(verify VerifyFunc, interval time.Duration, logger Logger) {
+	cm.mu.Lock()
+	for _, client := range cm.clients {
+		cm.excluded[client] = UnverifiedReason
+	}
+	cm.mu.Unlock()
+
+	cm.VerifyClients(verify, logger)
+
+	if interval != 0 {
+		cm.run(interval, false, func() { cm.VerifyClients(verify, logger) })
+	} else {
+		cm.run(unverifiedRetryInterval, false, func() { cm.verifyClients(cm.unverified(), verify, logger) })
+	}
+}
+
+// Stop stops health checking and verifying the clients if started and waits
+// for them to exit.
+func (cm *ClientManager) Stop() {
+	cm.mu.Lock()
+	quit := cm.quit
+	cm.quit = nil
+	cm.mu.Unlock()
+
+	if quit == nil {
+		return
+	}
+
+	close(quit)
+	cm.wg.Wait()
+}
+
+// run runs the given function in a go-routine every given interval until the
+// manager is stopped. If immediate is true, it is also run once right away.
+func (cm *ClientManager) run(interval time.Duration, immediate bool, fn func()) {
+	cm.mu.Lock()
+	if cm.quit == nil {
+		cm.quit = make(chan struct{})
+	}
+	quit := cm.quit
+	cm.mu.Unlock()
+
+	cm.wg.Add(1)
+
+	go func() {
+		defer cm.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		if immediate {
+			fn()
+		}
+
+		for {
+			select {
+			case <-quit:
+				return
+
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+}
+
+// VerifyClients verifies all clients once, excluding clients that do not serve
+// the expected chain and re-admitting excluded clients that do again. Clients
+// that cannot be verified (e.g. they are unreachable) keep their state, so a
+// client that has never been verified remains excluded.
+func (cm *ClientManager) VerifyClients(verify VerifyFunc, logger Logger) {
+	cm.verifyClients(cm.clients, verify, logger)
+}
+
+// verifyClients verifies the given clients once similar to VerifyClients.
+func (cm *ClientManager) verifyClients(clients []string, verify VerifyFunc, logger Logger) {
+	mismatches := make(map[string]string)
+	errs := make(map[string]error)
+
+	for _, client := range clients {
+		mismatch, err := verify(client)
+		if err != nil {
+			errs[client] = err
+			continue
+		}
+
+		mismatches[client] = mismatch
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	for _, client := range clients {
+		reason, excluded := cm.excluded[client]
+
+		if err, ok := errs[client]; ok {
+			if reason == UnverifiedReason {
+				logger.Warnf("failed to verify client %s; keeping it excluded: %v", client, err)
+			} else {
+				logger.Debugf("failed to verify client %s: %v", client, err)
+			}
+
+			continue
+		}
+
+		switch mismatch := mismatches[client]; {
+		case mismatch != "" && (!excluded || reason == UnverifiedReason):
+			logger.Errorf("excluding client %s: %s", client, mismatch)
+			cm.excluded[client] = mismatch
+
+		case mismatch != "":
+			cm.excluded[client] = mismatch
+
+		case reason == UnverifiedReason:
+			logger.Infof("admitting verified client %s", client)
+			delete(cm.excluded, client)
+
+		case excluded:
+			logger.Infof("re-admitting verified client %s", client)
+			delete(cm.excluded, client)
+		}
+	}
+}
+
+// unverified returns all the clients that are excluded as not verified yet.
+func (cm *ClientManager) unverified() []string {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	var clients []string
+	for _, client := range cm.clients {
+		if cm.excluded[client] == UnverifiedReason {
+			clients = append(clients, client)
+		}
+	}
+
+	return clients
+}
+
+// CheckHealth health checks all clients once, ejecting unhealthy clients and
+// re-admitting clients that have become healthy again.
+func (cm *ClientManager) CheckHealth(check HealthCheckFunc, maxLag int64, logger Logger) {
+	heights := make(map[string]int64)
+	errs := make(map[string]error)
+
+	var maxHeight int64
+	for _, client := range cm.clients {
+		height, err := check(client)
+		if err != nil {
+			errs[client] = err
+			continue
+		}
+
+		heights[client] = height
+		if height > maxHeight {
+			maxHeight = height
+		}
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	for _, client := range cm.clients {
+		var reason string
+
+		if err, ok := errs[client]; ok {
+			reason = fmt.Sprintf("unreachable: %v", err)
+		} else if lag := maxHeight - heights[client]; maxLag > 0 && lag > maxLag {
+			reason = fmt.Sprintf("%d blocks behind", lag)
+		}
+
+		switch {
+		case reason != "" && !cm.unhealthy[client]:
+			logger.Warnf("ejecting unhealthy client %s: %s", client, reason)
+			cm.unhealthy[client] = true
+
+		case reason == "" && cm.unhealthy[client]:
+			logger.Infof("re-admitting healthy client %s", client)
+			delete(cm.unhealthy, client)
+		}
+	}
+}
+
+// throttled returns true if the given client is currently throttled.
+func (cm *ClientManager) throttled(client string) bool {
+	cm.mu.Lock()
+	limiter := cm.limiters[client]
+	cm.mu.Unlock()
+
+	return limiter.throttled()
+}
+
+// clientOf returns the client the given url belongs to or an empty string if
+// it belongs to none. A url belongs to a client if it is the client itself or
+// continues it with a path or query, so that e.g. a client on port 1317 does
+// not match a url on port 13170.
+func (cm *ClientManager) clientOf(url string) string {
+	for _, client := range cm.clients {
+		if !strings.HasPrefix(url, client) {
+			continue
+		}
+
+		rest := url[len(client):]
+		if rest == "" || strings.HasSuffix(client, "/") || rest[0] == '/' || rest[0] == '?' {
+			return client
+		}
+	}
+
+	return ""
+}
+
+// failover returns the healthy clients that are not throttled to retry a
+// failed request to the given url with in the order following the url's
+// client.
+func (cm *ClientManager) failover(url string) []string {
+	failed := cm.clientOf(url)
+	if failed == "" {
+		return nil
+	}
+
+	healthy := cm.Healthy()
+
+	start := 0
+	for i, client := range healthy {
+		if client == failed {
+			start = i + 1
+			break
+		}
+	}
+
+	var clients []string
+	for i := 0; i < len(healthy); i++ {
+		client := healthy[(start+i)%len(healthy)]
+		if client == failed || cm.throttled(client) {
+			continue
+		}
+
+		clients = append(clients, client)
+	}
+
+	return clients
 }
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate limiting related defaults.
+const (
+	// defaultRetryAfter defines the duration a client is throttled for if it
+	// responds with too many requests without a valid Retry-After header.
+	defaultRetryAfter = time.Second
+
+	// maxRetryAfter defines the maximum duration a client is throttled for
+	// regardless of its Retry-After header.
+	maxRetryAfter = time.Minute
+)
+
+// ThrottledError defines an error of a request the server rejected with too
+// many requests (HTTP 429). The duration after which the server accepts
+// requests again is zero if unknown.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	if e.RetryAfter == 0 {
+		return "received too many requests status"
+	}
+
+	return fmt.Sprintf("received too many requests status, retry after %s", e.RetryAfter)
+}
+
+// parseRetryAfter parses the value of a Retry-After header given either as
+// seconds or as an HTTP date. Zero is returned if the value is invalid.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+
+	if seconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// tokenBucket implements a token bucket rate limiter of a single client that
+// additionally blocks all requests while the client is throttled. A zero rate
+// disables rate limiting.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	until  time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token from the bucket and returns the duration to wait for
+// before a request may be sent. Tokens are reserved ahead of time so that
+// concurrent requests are spaced out by the rate.
+func (tb *tokenBucket) reserve() time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := time.Now()
+
+	var wait time.Duration
+	if tb.rate > 0 {
+		tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+		if tb.tokens > tb.burst {
+			tb.tokens = tb.burst
+		}
+
+		tb.last = now
+		tb.tokens--
+
+		if tb.tokens < 0 {
+			wait = time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+		}
+	}
+
+	if until := tb.until.Sub(now); until > wait {
+		wait = until
+	}
+
+	return wait
+}
+
+// throttle blocks all requests for the given duration, bounded by the maximum
+// Retry-After. A zero duration throttles for the default Retry-After.
+func (tb *tokenBucket) throttle(d time.Duration) {
+	switch {
+	case d <= 0:
+		d = defaultRetryAfter
+
+	case d > maxRetryAfter:
+		d = maxRetryAfter
+	}
+
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(tb.until) {
+		tb.until = until
+	}
+}
+
+// throttled returns true if requests are currently blocked.
+func (tb *tokenBucket) throttled() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	return time.Now().Before(tb.until)
+}
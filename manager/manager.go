@@ -2,6 +2,7 @@ package manager
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/alexanderbez/titan/alerts"
@@ -23,13 +24,19 @@ type (
 	// Manager implements an monitoring manager that is responsible for executing
 	// various monitors and alerting a series of alerting targets. For every
 	// successful monitor whose response/result has not been seen before, will be
-	// sent to each alert target specified.
+	// sent to each alert target specified. A manager is created per monitored
+	// chain where alerts and monitor executions are persisted under the chain's
+	// namespaces.
 	Manager struct {
 		db       core.DB
 		logger   core.Logger
+		chainID  string
 		monitors []monitor.Monitor
 		alerters []alerts.Alerter
 		ticker   *time.Ticker
+
+		alertsNamespace   []byte
+		monitorsNamespace []byte
 	}
 
 	monitorExec struct {
@@ -46,12 +53,21 @@ func New(
 	monitors []monitor.Monitor, alerters []alerts.Alerter,
 ) Manager {
 
+	logger = logger.With("module", "manager")
+	if cfg.ChainID != "" {
+		logger = logger.With("chain", cfg.ChainID)
+	}
+
 	return Manager{
 		db:       db,
-		logger:   logger.With("module", "manager"),
+		logger:   logger,
+		chainID:  cfg.ChainID,
 		monitors: monitors,
 		alerters: alerters,
 		ticker:   time.NewTicker(time.Duration(cfg.PollInterval) * time.Second),
+
+		alertsNamespace:   core.ChainNamespace(cfg.ChainID, core.BadgerAlertsNamespace),
+		monitorsNamespace: core.ChainNamespace(cfg.ChainID, core.BadgerMonitorsNamespace),
 	}
 }
 
@@ -66,49 +82,68 @@ func newMonitorExec() *monitorExec {
 }
 
 // Start is responsible for starting the Manager's poller in a go-routine. It
-// will poll every config.PollInterval seconds. Errors are logged but do not
-// cause the poller or manager to exit.
+// will poll every config.PollInterval seconds. Polls never overlap; if a poll
+// takes longer than the interval, missed ticks are dropped. Errors are logged
+// but do not cause the poller or manager to exit.
 func (mngr Manager) Start() {
-	mngr.poll()
-
-	for {
+	for poll := uint64(1); ; poll++ {
+		mngr.poll(poll)
 		<-mngr.ticker.C
-		go mngr.poll()
 	}
 }
 
-// poll iterates over every monitor and attempts an execution. Upon successful
-// execution, the result's ID is checked against the DB. If it has not been
-// seen before, it will be sent to each alert target. Any error is logged.
-func (mngr Manager) poll() {
+// poll iterates over every monitor and attempts an execution. Monitors that
+// observe polls are notified of the start of the poll before any execution.
+// Upon successful execution, the result's ID is checked against the DB. If it
+// has not been seen before, it will be sent to each alert target. Any error is
+// logged. The poll's duration and the results of every execution and alert
+// are recorded as metrics.
+func (mngr Manager) poll(poll uint64) {
 	mngr.logger.Info("monitoring for new alerts to trigger...")
 	mExec := newMonitorExec()
 
+	for _, mon := range mngr.monitors {
+		if po, ok := mon.(monitor.PollObserver); ok {
+			po.BeginPoll(poll)
+		}
+	}
+
+	metrics := core.DefaultMetrics()
+
+	start := time.Now()
+	defer func() {
+		metrics.PollDuration.WithLabelValues(mngr.chainID).Observe(time.Since(start).Seconds())
+	}()
+
 	for _, mon := range mngr.monitors {
 		res, id, err := mon.Exec()
 		if err != nil {
 			mngr.logger.Debugf("failed to monitor %s; skipping alert: %v", mon.Name(), err)
 			mExec.FailedMonitors = append(mExec.FailedMonitors, mon.Name())
+			metrics.MonitorExecutions.WithLabelValues(mngr.chainID, mon.Name(), "failure").Inc()
 		} else {
 			// The monitor was successful and but may be regarded as seen before.
 			mExec.SuccessfulMonitors = append(mExec.SuccessfulMonitors, mon.Name())
+			metrics.MonitorExecutions.WithLabelValues(mngr.chainID, mon.Name(), "success").Inc()
 
 			// Attempt to trigger alert for the monitor's response if it has not been
 			// seen before (based on ID).
 			for _, alerter := range mngr.alerters {
-				ok, err := mngr.db.Has(core.BadgerAlertsNamespace, id)
+				ok, err := mngr.db.Has(mngr.alertsNamespace, id)
 				if !ok && err == nil {
 					// Database successfully checked and no previous monitor response has
 					// been found.
-					err := alerter.Alert(res, mon.Memo())
+					err := alerter.Alert(res, mngr.memo(mon))
 					if err != nil {
 						mExec.FailedAlerts = append(mExec.FailedAlerts, alerter.Name())
+						metrics.AlertsFailed.WithLabelValues(mngr.chainID, alerter.Name()).Inc()
 					} else {
 						mExec.SuccessfulAlerts = append(mExec.SuccessfulAlerts, alerter.Name())
+						metrics.AlertsSent.WithLabelValues(mngr.chainID, alerter.Name()).Inc()
 
 						// Persist the monitor response by the ID with a TTL to prevent
 						// alerting spam.
-						err := mngr.db.SetWithTTL(core.BadgerAlertsNamespace, id, res, alertTTL)
+						err := mngr.db.SetWithTTL(mngr.alertsNamespace, id, res, alertTTL)
 						if err != nil {
 							mngr.logger.Debugf("failed to persist alert: %v", err)
 						}
@@ -124,13 +159,23 @@ func (mngr Manager) poll() {
 	}
 }
 
+// memo returns the alert memo of a monitor which is prefixed with the chain ID
+// if one is configured.
+func (mngr Manager) memo(mon monitor.Monitor) string {
+	if mngr.chainID == "" {
+		return mon.Memo()
+	}
+
+	return fmt.Sprintf("[%s] %s", mngr.chainID, mon.Memo())
+}
+
 func (mngr Manager) saveLatestMonitorExec(mExec *monitorExec) error {
 	raw, err := json.Marshal(mExec)
 	if err != nil {
 		return err
 	}
 
-	err = mngr.db.Set(core.BadgerMonitorsNamespace, MonitorExecKey, raw)
+	err = mngr.db.Set(mngr.monitorsNamespace, MonitorExecKey, raw)
 	if err != nil {
 		return err
 	}
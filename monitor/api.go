@@ -0,0 +1,1245 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/alexanderbez/titan/config"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/wire"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/gov"
+	"github.com/cosmos/cosmos-sdk/x/slashing"
+	"github.com/cosmos/cosmos-sdk/x/stake"
+	staketypes "github.com/cosmos/cosmos-sdk/x/stake/types"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	cryptoAmino "github.com/tendermint/tendermint/crypto/encoding/amino"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	"github.com/tendermint/tendermint/libs/bech32"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// gatewayPageLimit defines the page size of paginated gateway API queries.
+const gatewayPageLimit = 10000
+
+// gatewayTxSearchLimit defines the number of most recent transactions returned
+// by a gateway API transaction search.
+const gatewayTxSearchLimit = 100
+
+// proposalStatusAll defines a proposal status matching proposals of any status.
+const proposalStatusAll gov.ProposalStatus = 0x00
+
+// chainAPI defines the queries monitors perform against LCD clients. Each
+// query is split into the path requested from a client and the decoding of
+// the response into the types monitors act on, so that all requests still go
+// through the client manager (i.e. failover and quorum reads).
+type chainAPI interface {
+	// BlockPath returns the path of the block at a given height or of the
+	// latest block if the height is zero.
+	BlockPath(height int64) string
+	DecodeBlock(body []byte) (*ctypes.ResultBlock, error)
+
+	ValidatorsPath() string
+	DecodeValidators(body []byte) ([]staketypes.BechValidator, error)
+
+	// ProposalsPath returns the path of all proposals with a given status or of
+	// all proposals if the status is proposalStatusAll.
+	ProposalsPath(status gov.ProposalStatus) string
+	DecodeProposals(body []byte) ([]gov.Proposal, error)
+
+	// ProposalPath returns the path of a single proposal from which
+	// DecodeProposal decodes the proposal or nil if it does not exist. A
+	// proposal that does not exist may also result in a not found status.
+	ProposalPath(proposalID int64) string
+	DecodeProposal(body []byte) (gov.Proposal, error)
+
+	VotesPath(proposalID int64) string
+	DecodeVotes(body []byte) ([]gov.Vote, error)
+
+	BalancePath(address string) (string, error)
+	DecodeBalance(body []byte) (sdk.Coins, error)
+
+	SigningInfoPath(val staketypes.BechValidator) (string, error)
+	DecodeSigningInfo(body []byte) (slashing.ValidatorSigningInfo, error)
+
+	SelfDelegationPath(operator string) (string, error)
+	DecodeSelfDelegationShares(body []byte) (sdk.Rat, error)
+
+	// TxSearchPaths returns the paths of the transaction searches that
+	// together find all unbonding delegations and redelegations from a
+	// validator operator.
+	TxSearchPaths(operator string) ([]string, error)
+	DecodeTxs(body []byte) ([]txInfo, error)
+
+	// NextPagePath returns the path of the page following a given response to
+	// a paginated path (i.e. validators, proposals, votes and balances) or an
+	// empty path if the response is the last page.
+	NextPagePath(path string, body []byte) (string, error)
+
+	// NodeInfoPath returns the path of the node info of a client from which
+	// DecodeChainID decodes the chain ID the client's node is running.
+	NodeInfoPath() string
+	DecodeChainID(body []byte) (string, error)
+
+	// Operator returns the Bech32 operator address, as given in validator
+	// filters, of a validator's owner.
+	Operator(owner sdk.AccAddress) string
+
+	// ConsPubKey decodes a Bech32 consensus public key as given in validator
+	// filters.
+	ConsPubKey(pubKey string) (crypto.PubKey, error)
+}
+
+// newChainAPI returns the chain API of the configured network.
+func newChainAPI(cfg config.Config) chainAPI {
+	if cfg.Network.API == config.APIGateway {
+		return newGatewayAPI(cfg)
+	}
+
+	return newLegacyAPI(cfg)
+}
+
+// ----------------------------------------------------------------------------
+// Legacy API
+
+// legacyAPI implements the chainAPI against the LCD of Cosmos SDK v0.24 which
+// responds with Amino JSON.
+type legacyAPI struct {
+	codec    *wire.Codec
+	prefixes config.Bech32
+}
+
+func newLegacyAPI(cfg config.Config) legacyAPI {
+	codec := wire.NewCodec()
+	sdk.RegisterWire(codec)
+	auth.RegisterWire(codec)
+	stake.RegisterWire(codec)
+	gov.RegisterWire(codec)
+	ctypes.RegisterAmino(codec)
+
+	return legacyAPI{codec: codec, prefixes: cfg.Network.Bech32Prefixes()}
+}
+
+func (api legacyAPI) BlockPath(height int64) string {
+	if height == 0 {
+		return "/blocks/latest"
+	}
+
+	return fmt.Sprintf("/blocks/%d", height)
+}
+
+func (api legacyAPI) DecodeBlock(body []byte) (*ctypes.ResultBlock, error) {
+	var block *ctypes.ResultBlock
+	if err := api.codec.UnmarshalJSON(body, &block); err != nil {
+		return nil, err
+	}
+
+	if block == nil || block.Block == nil {
+		return nil, errors.New("received empty block")
+	}
+
+	return block, nil
+}
+
+func (api legacyAPI) ValidatorsPath() string { return "/stake/validators" }
+
+func (api legacyAPI) DecodeValidators(body []byte) ([]staketypes.BechValidator, error) {
+	var vals []staketypes.BechValidator
+	err := api.codec.UnmarshalJSON(body, &vals)
+	return vals, err
+}
+
+func (api legacyAPI) ProposalsPath(status gov.ProposalStatus) string {
+	if status == proposalStatusAll {
+		return "/gov/proposals"
+	}
+
+	return fmt.Sprintf("/gov/proposals?status=%s", status.String())
+}
+
+func (api legacyAPI) DecodeProposals(body []byte) ([]gov.Proposal, error) {
+	var proposals []gov.Proposal
+	err := api.codec.UnmarshalJSON(body, &proposals)
+	return proposals, err
+}
+
+func (api legacyAPI) ProposalPath(proposalID int64) string {
+	return fmt.Sprintf("/gov/proposals/%d", proposalID)
+}
+
+// DecodeProposal returns a single proposal. A proposal that does not exist
+// results in a plain text error message instead of an error status.
+//
+// NOTE: The message is the same if the client fails to query the proposal.
+func (api legacyAPI) DecodeProposal(body []byte) (gov.Proposal, error) {
+	if bytes.HasSuffix(bytes.TrimSpace(body), []byte("does not exist")) {
+		return nil, nil
+	}
+
+	var proposal gov.Proposal
+	if err := api.codec.UnmarshalJSON(body, &proposal); err != nil {
+		return nil, err
+	}
+
+	return proposal, nil
+}
+
+func (api legacyAPI) VotesPath(proposalID int64) string {
+	return fmt.Sprintf("/gov/proposals/%d/votes", proposalID)
+}
+
+func (api legacyAPI) DecodeVotes(body []byte) ([]gov.Vote, error) {
+	var votes []gov.Vote
+	err := api.codec.UnmarshalJSON(body, &votes)
+	return votes, err
+}
+
+func (api legacyAPI) BalancePath(address string) (string, error) {
+	return fmt.Sprintf("/accounts/%s", address), nil
+}
+
+// DecodeBalance returns the coins owned by an account. An account that does
+// not exist yet results in an empty response and is treated as having no
+// coins.
+func (api legacyAPI) DecodeBalance(body []byte) (sdk.Coins, error) {
+	if len(body) == 0 {
+		return sdk.Coins{}, nil
+	}
+
+	var account auth.Account
+	if err := api.codec.UnmarshalJSON(body, &account); err != nil {
+		return nil, err
+	}
+
+	return account.GetCoins(), nil
+}
+
+func (api legacyAPI) SigningInfoPath(val staketypes.BechValidator) (string, error) {
+	return fmt.Sprintf("/slashing/signing_info/%s", val.PubKey), nil
+}
+
+func (api legacyAPI) DecodeSigningInfo(body []byte) (signingInfo slashing.ValidatorSigningInfo, err error) {
+	err = api.codec.UnmarshalJSON(body, &signingInfo)
+	return signingInfo, err
+}
+
+func (api legacyAPI) SelfDelegationPath(operator string) (string, error) {
+	return fmt.Sprintf("/stake/delegators/%s/delegations/%s", operator, operator), nil
+}
+
+// DecodeSelfDelegationShares returns the shares of a delegation. A missing
+// delegation results in an empty response and is treated as having no shares.
+func (api legacyAPI) DecodeSelfDelegationShares(body []byte) (sdk.Rat, error) {
+	if len(body) == 0 {
+		return sdk.ZeroRat(), nil
+	}
+
+	var delegation struct {
+		Shares string `json:"shares"`
+	}
+
+	if err := api.codec.UnmarshalJSON(body, &delegation); err != nil {
+		return sdk.Rat{}, err
+	}
+
+	shares, sdkErr := sdk.NewRatFromDecimal(delegation.Shares, 10)
+	if sdkErr != nil {
+		return sdk.Rat{}, errors.New(sdkErr.Error())
+	}
+
+	return shares, nil
+}
+
+func (api legacyAPI) TxSearchPaths(operator string) ([]string, error) {
+	tag := url.QueryEscape(fmt.Sprintf("%s='%s'", sdk.TagSrcValidator, operator))
+	return []string{fmt.Sprintf("/txs?tag=%s", tag)}, nil
+}
+
+func (api legacyAPI) DecodeTxs(body []byte) ([]txInfo, error) {
+	var txs []txInfo
+	err := api.codec.UnmarshalJSON(body, &txs)
+	return txs, err
+}
+
+// NextPagePath implements chainAPI. The legacy API does not paginate
+// responses.
+func (api legacyAPI) NextPagePath(path string, body []byte) (string, error) {
+	return "", nil
+}
+
+func (api legacyAPI) NodeInfoPath() string { return "/node_info" }
+
+func (api legacyAPI) DecodeChainID(body []byte) (string, error) {
+	var nodeInfo struct {
+		Network string `json:"network"`
+	}
+
+	if err := json.Unmarshal(body, &nodeInfo); err != nil {
+		return "", err
+	}
+
+	return nodeInfo.Network, nil
+}
+
+func (api legacyAPI) Operator(owner sdk.AccAddress) string {
+	return encodeBech32(api.prefixes.ValAddr, owner)
+}
+
+func (api legacyAPI) ConsPubKey(pubKey string) (crypto.PubKey, error) {
+	return decodeBech32PubKey(pubKey, api.prefixes.ConsPub)
+}
+
+// ----------------------------------------------------------------------------
+// Gateway API
+
+// gatewayAPI implements the chainAPI against the gRPC-gateway REST endpoints
+// of current Cosmos SDK versions which respond with Protobuf JSON. Responses
+// are converted into the types of the supported SDK version. Proposals keep
+// their deposit and voting end times as they are not defined in blocks.
+type gatewayAPI struct {
+	signedBlocksWindow int64
+	prefixes           config.Bech32
+}
+
+func newGatewayAPI(cfg config.Config) gatewayAPI {
+	return gatewayAPI{
+		signedBlocksWindow: cfg.Slashing.SignedBlocksWindow,
+		prefixes:           cfg.Network.Bech32Prefixes(),
+	}
+}
+
+type (
+	gatewayCoin struct {
+		Denom  string `json:"denom"`
+		Amount string `json:"amount"`
+	}
+
+	gatewayVote struct {
+		Height           int64     `json:"height,string"`
+		Round            int       `json:"round"`
+		Timestamp        time.Time `json:"timestamp"`
+		ValidatorAddress []byte    `json:"validator_address"`
+		ValidatorIndex   int       `json:"validator_index"`
+		Signature        []byte    `json:"signature"`
+	}
+
+	gatewayCommitSig struct {
+		BlockIDFlag      string    `json:"block_id_flag"`
+		ValidatorAddress []byte    `json:"validator_address"`
+		Timestamp        time.Time `json:"timestamp"`
+		Signature        []byte    `json:"signature"`
+	}
+
+	gatewayCommit struct {
+		Height     int64              `json:"height,string"`
+		Round      int                `json:"round"`
+		Signatures []gatewayCommitSig `json:"signatures"`
+	}
+
+	gatewayDuplicateVote struct {
+		VoteA gatewayVote `json:"vote_a"`
+	}
+
+	gatewayEvidence struct {
+		DuplicateVoteEvidence *gatewayDuplicateVote `json:"duplicate_vote_evidence"`
+	}
+
+	gatewayBlock struct {
+		Header struct {
+			ChainID string    `json:"chain_id"`
+			Height  int64     `json:"height,string"`
+			Time    time.Time `json:"time"`
+		} `json:"header"`
+		Data struct {
+			Txs [][]byte `json:"txs"`
+		} `json:"data"`
+		Evidence struct {
+			Evidence []gatewayEvidence `json:"evidence"`
+		} `json:"evidence"`
+		LastCommit *gatewayCommit `json:"last_commit"`
+	}
+
+	gatewayValidator struct {
+		OperatorAddress string `json:"operator_address"`
+		ConsensusPubKey struct {
+			Type string `json:"@type"`
+			Key  []byte `json:"key"`
+		} `json:"consensus_pubkey"`
+		Jailed          bool   `json:"jailed"`
+		Status          string `json:"status"`
+		Tokens          string `json:"tokens"`
+		DelegatorShares string `json:"delegator_shares"`
+		Description     struct {
+			Moniker  string `json:"moniker"`
+			Identity string `json:"identity"`
+			Website  string `json:"website"`
+			Details  string `json:"details"`
+		} `json:"description"`
+		Commission struct {
+			CommissionRates struct {
+				Rate          string `json:"rate"`
+				MaxRate       string `json:"max_rate"`
+				MaxChangeRate string `json:"max_change_rate"`
+			} `json:"commission_rates"`
+		} `json:"commission"`
+	}
+
+	gatewayUpgradePlan struct {
+		Name   string `json:"name"`
+		Height int64  `json:"height,string"`
+	}
+
+	gatewayProposalMsg struct {
+		Type        string              `json:"@type"`
+		Title       string              `json:"title"`
+		Description string              `json:"description"`
+		Plan        *gatewayUpgradePlan `json:"plan"`
+		Content     *gatewayProposalMsg `json:"content"`
+	}
+
+	gatewayProposalJSON struct {
+		ID               int64                `json:"id,string"`
+		Messages         []gatewayProposalMsg `json:"messages"`
+		Status           string               `json:"status"`
+		FinalTallyResult struct {
+			YesCount        string `json:"yes_count"`
+			AbstainCount    string `json:"abstain_count"`
+			NoCount         string `json:"no_count"`
+			NoWithVetoCount string `json:"no_with_veto_count"`
+		} `json:"final_tally_result"`
+		DepositEndTime time.Time     `json:"deposit_end_time"`
+		TotalDeposit   []gatewayCoin `json:"total_deposit"`
+		VotingEndTime  *time.Time    `json:"voting_end_time"`
+		Title          string        `json:"title"`
+		Summary        string        `json:"summary"`
+	}
+
+	gatewayVoteOption struct {
+		Option string `json:"option"`
+	}
+
+	gatewayVoteJSON struct {
+		ProposalID int64               `json:"proposal_id,string"`
+		Voter      string              `json:"voter"`
+		Options    []gatewayVoteOption `json:"options"`
+	}
+
+	gatewayTxMsg struct {
+		Type                string       `json:"@type"`
+		DelegatorAddress    string       `json:"delegator_address"`
+		ValidatorAddress    string       `json:"validator_address"`
+		ValidatorSrcAddress string       `json:"validator_src_address"`
+		ValidatorDstAddress string       `json:"validator_dst_address"`
+		Amount              *gatewayCoin `json:"amount"`
+	}
+
+	gatewayTxResponse struct {
+		Height int64  `json:"height,string"`
+		TxHash string `json:"txhash"`
+		Code   uint32 `json:"code"`
+		Tx     struct {
+			Body struct {
+				Messages []gatewayTxMsg `json:"messages"`
+			} `json:"body"`
+		} `json:"tx"`
+	}
+
+	gatewaySigningInfo struct {
+		StartHeight         int64     `json:"start_height,string"`
+		IndexOffset         int64     `json:"index_offset,string"`
+		JailedUntil         time.Time `json:"jailed_until"`
+		MissedBlocksCounter int64     `json:"missed_blocks_counter,string"`
+	}
+
+	// gatewayProposal defines a proposal decoded from the gateway API. Deposit
+	// and voting periods end at a given time and software upgrades contain a
+	// structured upgrade plan.
+	gatewayProposal struct {
+		gov.TextProposal
+
+		depositEndTime time.Time
+		votingEndTime  time.Time
+		plan           *gatewayUpgradePlan
+	}
+
+	// gatewayUndelegationMsg defines an unbonding delegation or redelegation
+	// decoded from the gateway API where the undelegated amount is given in
+	// tokens instead of shares.
+	gatewayUndelegationMsg struct {
+		sdk.Msg
+
+		tokens sdk.Rat
+	}
+
+	// gatewayDuplicateVoteEvidence defines duplicate vote evidence decoded from
+	// the gateway API which only contains the validator's address and not its
+	// public key.
+	gatewayDuplicateVoteEvidence struct {
+		height  int64
+		address crypto.Address
+	}
+)
+
+var _ tmtypes.Evidence = (*gatewayDuplicateVoteEvidence)(nil)
+
+func (e *gatewayDuplicateVoteEvidence) Height() int64   { return e.height }
+func (e *gatewayDuplicateVoteEvidence) Address() []byte { return e.address }
+
+func (e *gatewayDuplicateVoteEvidence) Hash() []byte {
+	return tmhash.Sum([]byte(e.String()))
+}
+
+func (e *gatewayDuplicateVoteEvidence) Verify(chainID string, pubKey crypto.PubKey) error {
+	return errors.New("gateway evidence cannot be verified")
+}
+
+func (e *gatewayDuplicateVoteEvidence) Equal(other tmtypes.Evidence) bool {
+	return bytes.Equal(e.Hash(), other.Hash())
+}
+
+func (e *gatewayDuplicateVoteEvidence) String() string {
+	return fmt.Sprintf("DuplicateVoteEvidence{%s/%d}", e.address, e.height)
+}
+
+func (api gatewayAPI) BlockPath(height int64) string {
+	if height == 0 {
+		return "/cosmos/base/tendermint/v1beta1/blocks/latest"
+	}
+
+	return fmt.Sprintf("/cosmos/base/tendermint/v1beta1/blocks/%d", height)
+}
+
+func (api gatewayAPI) DecodeBlock(body []byte) (*ctypes.ResultBlock, error) {
+	var resp struct {
+		BlockID struct {
+			Hash []byte `json:"hash"`
+		} `json:"block_id"`
+		Block *gatewayBlock `json:"block"`
+	}
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Block == nil {
+		return nil, errors.New("received empty block")
+	}
+
+	block := resp.Block.toResultBlock()
+	block.BlockMeta = &tmtypes.BlockMeta{
+		BlockID: tmtypes.BlockID{Hash: resp.BlockID.Hash},
+		Header:  block.Block.Header,
+	}
+
+	return block, nil
+}
+
+func (gb *gatewayBlock) toResultBlock() *ctypes.ResultBlock {
+	txs := make([]tmtypes.Tx, len(gb.Data.Txs))
+	for i, tx := range gb.Data.Txs {
+		txs[i] = tmtypes.Tx(tx)
+	}
+
+	var evidence []tmtypes.Evidence
+	for _, e := range gb.Evidence.Evidence {
+		if e.DuplicateVoteEvidence != nil {
+			evidence = append(evidence, &gatewayDuplicateVoteEvidence{
+				height:  e.DuplicateVoteEvidence.VoteA.Height,
+				address: e.DuplicateVoteEvidence.VoteA.ValidatorAddress,
+			})
+		}
+	}
+
+	commit := &tmtypes.Commit{}
+	if gb.LastCommit != nil {
+		commit.Precommits = make([]*tmtypes.Vote, len(gb.LastCommit.Signatures))
+
+		for i, sig := range gb.LastCommit.Signatures {
+			// absent validators are nil precommits as in the supported version
+			if sig.BlockIDFlag == "BLOCK_ID_FLAG_ABSENT" {
+				continue
+			}
+
+			commit.Precommits[i] = &tmtypes.Vote{
+				ValidatorAddress: sig.ValidatorAddress,
+				ValidatorIndex:   i,
+				Height:           gb.LastCommit.Height,
+				Round:            gb.LastCommit.Round,
+				Timestamp:        sig.Timestamp,
+				Type:             tmtypes.VoteTypePrecommit,
+				Signature:        sig.Signature,
+			}
+		}
+	}
+
+	block := tmtypes.MakeBlock(gb.Header.Height, txs, commit, evidence)
+	block.Header.ChainID = gb.Header.ChainID
+	block.Header.Time = gb.Header.Time
+
+	return &ctypes.ResultBlock{Block: block}
+}
+
+func (api gatewayAPI) ValidatorsPath() string {
+	return fmt.Sprintf("/cosmos/staking/v1beta1/validators?pagination.limit=%d", gatewayPageLimit)
+}
+
+func (api gatewayAPI) DecodeValidators(body []byte) ([]staketypes.BechValidator, error) {
+	var resp struct {
+		Validators []gatewayValidator `json:"validators"`
+	}
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	vals := make([]staketypes.BechValidator, len(resp.Validators))
+
+	for i, gv := range resp.Validators {
+		val, err := gv.toBechValidator(api.prefixes.ConsPub)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid validator %s", gv.OperatorAddress)
+		}
+
+		vals[i] = val
+	}
+
+	return vals, nil
+}
+
+// toBechValidator returns the validator where its consensus public key is
+// encoded with the given Bech32 prefix.
+func (gv gatewayValidator) toBechValidator(consPubPrefix string) (val staketypes.BechValidator, err error) {
+	_, owner, err := bech32.DecodeAndConvert(gv.OperatorAddress)
+	if err != nil {
+		return val, err
+	}
+
+	var pubKey crypto.PubKey
+
+	switch gv.ConsensusPubKey.Type {
+	case "/cosmos.crypto.ed25519.PubKey":
+		var pk ed25519.PubKeyEd25519
+		copy(pk[:], gv.ConsensusPubKey.Key)
+		pubKey = pk
+
+	case "/cosmos.crypto.secp256k1.PubKey":
+		var pk secp256k1.PubKeySecp256k1
+		copy(pk[:], gv.ConsensusPubKey.Key)
+		pubKey = pk
+
+	default:
+		return val, fmt.Errorf("unsupported consensus public key type %s", gv.ConsensusPubKey.Type)
+	}
+
+	val = staketypes.BechValidator{
+		Owner:   sdk.AccAddress(owner),
+		PubKey:  encodeBech32(consPubPrefix, pubKey.Bytes()),
+		Revoked: gv.Jailed,
+		Description: staketypes.Description{
+			Moniker:  gv.Description.Moniker,
+			Identity: gv.Description.Identity,
+			Website:  gv.Description.Website,
+			Details:  gv.Description.Details,
+		},
+	}
+
+	switch gv.Status {
+	case "BOND_STATUS_BONDED":
+		val.Status = sdk.Bonded
+
+	case "BOND_STATUS_UNBONDING":
+		val.Status = sdk.Unbonding
+
+	default:
+		val.Status = sdk.Unbonded
+	}
+
+	if val.Tokens, err = parseGatewayDec(gv.Tokens); err != nil {
+		return val, err
+	}
+
+	if val.DelegatorShares, err = parseGatewayDec(gv.DelegatorShares); err != nil {
+		return val, err
+	}
+
+	rates := gv.Commission.CommissionRates
+	if val.Commission, err = parseGatewayDec(rates.Rate); err != nil {
+		return val, err
+	}
+
+	if val.CommissionMax, err = parseGatewayDec(rates.MaxRate); err != nil {
+		return val, err
+	}
+
+	if val.CommissionChangeRate, err = parseGatewayDec(rates.MaxChangeRate); err != nil {
+		return val, err
+	}
+
+	return val, nil
+}
+
+func (api gatewayAPI) ProposalsPath(status gov.ProposalStatus) string {
+	path := fmt.Sprintf("/cosmos/gov/v1/proposals?pagination.limit=%d", gatewayPageLimit)
+
+	switch status {
+	case gov.StatusDepositPeriod:
+		path += "&proposal_status=PROPOSAL_STATUS_DEPOSIT_PERIOD"
+
+	case gov.StatusVotingPeriod:
+		path += "&proposal_status=PROPOSAL_STATUS_VOTING_PERIOD"
+
+	case gov.StatusPassed:
+		path += "&proposal_status=PROPOSAL_STATUS_PASSED"
+
+	case gov.StatusRejected:
+		path += "&proposal_status=PROPOSAL_STATUS_REJECTED"
+	}
+
+	return path
+}
+
+func (api gatewayAPI) DecodeProposals(body []byte) ([]gov.Proposal, error) {
+	var resp struct {
+		Proposals []gatewayProposalJSON `json:"proposals"`
+	}
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	proposals := make([]gov.Proposal, len(resp.Proposals))
+
+	for i, gp := range resp.Proposals {
+		proposal, err := gp.toProposal()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid proposal %d", gp.ID)
+		}
+
+		proposals[i] = proposal
+	}
+
+	return proposals, nil
+}
+
+func (api gatewayAPI) ProposalPath(proposalID int64) string {
+	return fmt.Sprintf("/cosmos/gov/v1/proposals/%d", proposalID)
+}
+
+func (api gatewayAPI) DecodeProposal(body []byte) (gov.Proposal, error) {
+	var resp struct {
+		Proposal *gatewayProposalJSON `json:"proposal"`
+	}
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Proposal == nil {
+		return nil, errors.New("received empty proposal")
+	}
+
+	proposal, err := resp.Proposal.toProposal()
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid proposal %d", resp.Proposal.ID)
+	}
+
+	return proposal, nil
+}
+
+func (gp gatewayProposalJSON) toProposal() (*gatewayProposal, error) {
+	proposal := &gatewayProposal{
+		TextProposal: gov.TextProposal{
+			ProposalID:   gp.ID,
+			Title:        gp.Title,
+			Description:  gp.Summary,
+			ProposalType: gov.ProposalTypeText,
+		},
+		depositEndTime: gp.DepositEndTime,
+	}
+
+	if gp.VotingEndTime != nil {
+		proposal.votingEndTime = *gp.VotingEndTime
+	}
+
+	for _, msg := range gp.Messages {
+		// proposals submitted as legacy content keep their title and description
+		// in the content
+		if msg.Content != nil {
+			if proposal.Title == "" {
+				proposal.Title = msg.Content.Title
+			}
+
+			if proposal.Description == "" {
+				proposal.Description = msg.Content.Description
+			}
+
+			msg = *msg.Content
+		}
+
+		if strings.HasSuffix(msg.Type, "MsgSoftwareUpgrade") || strings.HasSuffix(msg.Type, "SoftwareUpgradeProposal") {
+			proposal.ProposalType = gov.ProposalTypeSoftwareUpgrade
+			proposal.plan = msg.Plan
+		}
+	}
+
+	switch gp.Status {
+	case "PROPOSAL_STATUS_DEPOSIT_PERIOD":
+		proposal.Status = gov.StatusDepositPeriod
+
+	case "PROPOSAL_STATUS_VOTING_PERIOD":
+		proposal.Status = gov.StatusVotingPeriod
+
+	case "PROPOSAL_STATUS_PASSED":
+		proposal.Status = gov.StatusPassed
+
+	// proposals that passed but failed to execute did not take effect
+	case "PROPOSAL_STATUS_REJECTED", "PROPOSAL_STATUS_FAILED":
+		proposal.Status = gov.StatusRejected
+	}
+
+	var err error
+
+	tally := gp.FinalTallyResult
+	if proposal.TallyResult.Yes, err = parseGatewayDec(tally.YesCount); err != nil {
+		return nil, err
+	}
+
+	if proposal.TallyResult.Abstain, err = parseGatewayDec(tally.AbstainCount); err != nil {
+		return nil, err
+	}
+
+	if proposal.TallyResult.No, err = parseGatewayDec(tally.NoCount); err != nil {
+		return nil, err
+	}
+
+	if proposal.TallyResult.NoWithVeto, err = parseGatewayDec(tally.NoWithVetoCount); err != nil {
+		return nil, err
+	}
+
+	if proposal.TotalDeposit, err = parseGatewayCoins(gp.TotalDeposit); err != nil {
+		return nil, err
+	}
+
+	return proposal, nil
+}
+
+func (api gatewayAPI) VotesPath(proposalID int64) string {
+	return fmt.Sprintf("/cosmos/gov/v1/proposals/%d/votes?pagination.limit=%d", proposalID, gatewayPageLimit)
+}
+
+func (api gatewayAPI) DecodeVotes(body []byte) ([]gov.Vote, error) {
+	var resp struct {
+		Votes []gatewayVoteJSON `json:"votes"`
+	}
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	votes := make([]gov.Vote, len(resp.Votes))
+
+	for i, gv := range resp.Votes {
+		vote, err := gv.toVote()
+		if err != nil {
+			return nil, err
+		}
+
+		votes[i] = vote
+	}
+
+	return votes, nil
+}
+
+func (gv gatewayVoteJSON) toVote() (gov.Vote, error) {
+	_, voter, err := bech32.DecodeAndConvert(gv.Voter)
+	if err != nil {
+		return gov.Vote{}, errors.Wrapf(err, "invalid voter %s", gv.Voter)
+	}
+
+	vote := gov.Vote{Voter: sdk.AccAddress(voter), ProposalID: gv.ProposalID}
+
+	// only the first option of weighted votes is kept
+	if len(gv.Options) != 0 {
+		switch gv.Options[0].Option {
+		case "VOTE_OPTION_YES":
+			vote.Option = gov.OptionYes
+
+		case "VOTE_OPTION_ABSTAIN":
+			vote.Option = gov.OptionAbstain
+
+		case "VOTE_OPTION_NO":
+			vote.Option = gov.OptionNo
+
+		case "VOTE_OPTION_NO_WITH_VETO":
+			vote.Option = gov.OptionNoWithVeto
+		}
+	}
+
+	return vote, nil
+}
+
+func (api gatewayAPI) BalancePath(address string) (string, error) {
+	address, err := convertBech32(address, api.prefixes.AccAddr)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("/cosmos/bank/v1beta1/balances/%s?pagination.limit=%d", address, gatewayPageLimit), nil
+}
+
+func (api gatewayAPI) DecodeBalance(body []byte) (sdk.Coins, error) {
+	var resp struct {
+		Balances []gatewayCoin `json:"balances"`
+	}
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	return parseGatewayCoins(resp.Balances)
+}
+
+func (api gatewayAPI) SigningInfoPath(val staketypes.BechValidator) (string, error) {
+	address, err := gatewayConsAddress(val, api.prefixes)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("/cosmos/slashing/v1beta1/signing_infos/%s", address), nil
+}
+
+// gatewayConsAddress returns the Bech32 consensus address of a validator with
+// the given prefixes.
+func gatewayConsAddress(val staketypes.BechValidator, prefixes config.Bech32) (string, error) {
+	pubKey, err := decodeBech32PubKey(val.PubKey, prefixes.ConsPub)
+	if err != nil {
+		return "", err
+	}
+
+	return bech32.ConvertAndEncode(prefixes.ConsAddr, pubKey.Address())
+}
+
+// DecodeSigningInfo returns the signing info of a validator where the signed
+// blocks counter is derived from the number of missed blocks in the signed
+// blocks window.
+func (api gatewayAPI) DecodeSigningInfo(body []byte) (slashing.ValidatorSigningInfo, error) {
+	var resp struct {
+		ValSigningInfo *gatewaySigningInfo `json:"val_signing_info"`
+	}
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return slashing.ValidatorSigningInfo{}, err
+	}
+
+	if resp.ValSigningInfo == nil {
+		return slashing.ValidatorSigningInfo{}, errors.New("received empty signing info")
+	}
+
+	return resp.ValSigningInfo.toSigningInfo(api.signedBlocksWindow), nil
+}
+
+// toSigningInfo converts gateway signing info given the signed blocks window.
+func (info gatewaySigningInfo) toSigningInfo(signedBlocksWindow int64) slashing.ValidatorSigningInfo {
+	counted := info.IndexOffset
+	if counted > signedBlocksWindow {
+		counted = signedBlocksWindow
+	}
+
+	signed := counted - info.MissedBlocksCounter
+	if signed < 0 {
+		signed = 0
+	}
+
+	return slashing.ValidatorSigningInfo{
+		StartHeight:         info.StartHeight,
+		IndexOffset:         info.IndexOffset,
+		JailedUntil:         info.JailedUntil,
+		SignedBlocksCounter: signed,
+	}
+}
+
+func (api gatewayAPI) SelfDelegationPath(operator string) (string, error) {
+	validator, err := convertBech32(operator, api.prefixes.ValAddr)
+	if err != nil {
+		return "", err
+	}
+
+	delegator, err := convertBech32(operator, api.prefixes.AccAddr)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("/cosmos/staking/v1beta1/validators/%s/delegations/%s", validator, delegator), nil
+}
+
+// DecodeSelfDelegationShares returns the shares of a delegation. A missing
+// delegation results in a not found status instead of a response.
+func (api gatewayAPI) DecodeSelfDelegationShares(body []byte) (sdk.Rat, error) {
+	var resp struct {
+		DelegationResponse *struct {
+			Delegation struct {
+				Shares string `json:"shares"`
+			} `json:"delegation"`
+		} `json:"delegation_response"`
+	}
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return sdk.Rat{}, err
+	}
+
+	if resp.DelegationResponse == nil {
+		return sdk.Rat{}, errors.New("received empty delegation")
+	}
+
+	return parseGatewayDec(resp.DelegationResponse.Delegation.Shares)
+}
+
+// TxSearchPaths implements chainAPI. Unbonding delegations and redelegations
+// are searched for by their events where only the most recent transactions
+// are returned. The query is given both as events and as query as the latter
+// replaced the former in later SDK versions.
+func (api gatewayAPI) TxSearchPaths(operator string) ([]string, error) {
+	validator, err := convertBech32(operator, api.prefixes.ValAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, event := range []string{"unbond.validator", "redelegate.source_validator"} {
+		query := url.QueryEscape(fmt.Sprintf("%s='%s'", event, validator))
+		paths = append(paths, fmt.Sprintf(
+			"/cosmos/tx/v1beta1/txs?events=%s&query=%s&order_by=ORDER_BY_DESC&page=1&limit=%d",
+			query, query, gatewayTxSearchLimit,
+		))
+	}
+
+	return paths, nil
+}
+
+// DecodeTxs returns the searched transactions where only unbonding delegation
+// and redelegation messages are kept.
+func (api gatewayAPI) DecodeTxs(body []byte) ([]txInfo, error) {
+	var resp struct {
+		TxResponses []gatewayTxResponse `json:"tx_responses"`
+	}
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	txs := make([]txInfo, len(resp.TxResponses))
+
+	for i, gtx := range resp.TxResponses {
+		hash, err := hex.DecodeString(gtx.TxHash)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid transaction hash %s", gtx.TxHash)
+		}
+
+		var msgs []sdk.Msg
+		for _, gm := range gtx.Tx.Body.Messages {
+			msg, ok, err := gm.toMsg()
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid transaction %s", gtx.TxHash)
+			} else if ok {
+				msgs = append(msgs, msg)
+			}
+		}
+
+		txs[i] = txInfo{
+			Hash:   hash,
+			Height: gtx.Height,
+			Tx:     auth.StdTx{Msgs: msgs},
+			Result: abci.ResponseDeliverTx{Code: gtx.Code},
+		}
+	}
+
+	return txs, nil
+}
+
+// toMsg returns the unbonding delegation or redelegation of a message. False
+// is returned for any other message.
+func (gm gatewayTxMsg) toMsg() (sdk.Msg, bool, error) {
+	var (
+		msg sdk.Msg
+		err error
+	)
+
+	switch {
+	case strings.HasSuffix(gm.Type, ".MsgUndelegate"):
+		var unbonding stake.MsgBeginUnbonding
+		if unbonding.DelegatorAddr, err = decodeBech32Address(gm.DelegatorAddress); err != nil {
+			return nil, false, err
+		}
+
+		if unbonding.ValidatorAddr, err = decodeBech32Address(gm.ValidatorAddress); err != nil {
+			return nil, false, err
+		}
+
+		msg = unbonding
+
+	case strings.HasSuffix(gm.Type, ".MsgBeginRedelegate"):
+		var redelegation stake.MsgBeginRedelegate
+		if redelegation.DelegatorAddr, err = decodeBech32Address(gm.DelegatorAddress); err != nil {
+			return nil, false, err
+		}
+
+		if redelegation.ValidatorSrcAddr, err = decodeBech32Address(gm.ValidatorSrcAddress); err != nil {
+			return nil, false, err
+		}
+
+		if redelegation.ValidatorDstAddr, err = decodeBech32Address(gm.ValidatorDstAddress); err != nil {
+			return nil, false, err
+		}
+
+		msg = redelegation
+
+	default:
+		return nil, false, nil
+	}
+
+	tokens := sdk.ZeroRat()
+	if gm.Amount != nil {
+		if tokens, err = parseGatewayDec(gm.Amount.Amount); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return gatewayUndelegationMsg{Msg: msg, tokens: tokens}, true, nil
+}
+
+// NextPagePath implements chainAPI. The next page is requested with the next
+// key of the given response.
+func (api gatewayAPI) NextPagePath(path string, body []byte) (string, error) {
+	var resp struct {
+		Pagination *struct {
+			NextKey string `json:"next_key"`
+		} `json:"pagination"`
+	}
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", err
+	}
+
+	if resp.Pagination == nil || resp.Pagination.NextKey == "" {
+		return "", nil
+	}
+
+	return fmt.Sprintf("%s&pagination.key=%s", path, url.QueryEscape(resp.Pagination.NextKey)), nil
+}
+
+func (api gatewayAPI) NodeInfoPath() string {
+	return "/cosmos/base/tendermint/v1beta1/node_info"
+}
+
+func (api gatewayAPI) DecodeChainID(body []byte) (string, error) {
+	var resp struct {
+		DefaultNodeInfo *struct {
+			Network string `json:"network"`
+		} `json:"default_node_info"`
+	}
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", err
+	}
+
+	if resp.DefaultNodeInfo == nil {
+		return "", errors.New("received empty node info")
+	}
+
+	return resp.DefaultNodeInfo.Network, nil
+}
+
+func (api gatewayAPI) Operator(owner sdk.AccAddress) string {
+	return encodeBech32(api.prefixes.ValAddr, owner)
+}
+
+func (api gatewayAPI) ConsPubKey(pubKey string) (crypto.PubKey, error) {
+	return decodeBech32PubKey(pubKey, api.prefixes.ConsPub)
+}
+
+// parseGatewayDec parses an integer or decimal string with up to 18 decimals
+// as used by the gateway API. An empty string is parsed as zero.
+func parseGatewayDec(s string) (sdk.Rat, error) {
+	if s == "" {
+		return sdk.ZeroRat(), nil
+	}
+
+	rat, err := sdk.NewRatFromDecimal(s, 18)
+	if err != nil {
+		return sdk.Rat{}, errors.New(err.Error())
+	}
+
+	return rat, nil
+}
+
+// parseGatewayCoins parses coins as used by the gateway API.
+func parseGatewayCoins(gcs []gatewayCoin) (sdk.Coins, error) {
+	coins := make(sdk.Coins, 0, len(gcs))
+
+	for _, gc := range gcs {
+		amount, ok := sdk.NewIntFromString(gc.Amount)
+		if !ok {
+			return nil, fmt.Errorf("invalid coin amount %s", gc.Amount)
+		}
+
+		coins = append(coins, sdk.NewCoin(gc.Denom, amount))
+	}
+
+	return coins.Sort(), nil
+}
+
+// convertBech32 converts a Bech32 address to the given prefix.
+func convertBech32(address, prefix string) (string, error) {
+	_, bz, err := bech32.DecodeAndConvert(address)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid address %s", address)
+	}
+
+	return bech32.ConvertAndEncode(prefix, bz)
+}
+
+// decodeBech32Address decodes a Bech32 address of any prefix.
+func decodeBech32Address(address string) (sdk.AccAddress, error) {
+	_, bz, err := bech32.DecodeAndConvert(address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid address %s", address)
+	}
+
+	return sdk.AccAddress(bz), nil
+}
+
+// encodeBech32 encodes an address with the given prefix. Encoding only fails
+// for invalid prefixes which are rejected by the configuration's validation.
+func encodeBech32(prefix string, address []byte) string {
+	s, err := bech32.ConvertAndEncode(prefix, address)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// decodeBech32PubKey decodes an Amino encoded public key from its Bech32
+// encoding with the given prefix.
+func decodeBech32PubKey(pubKey, prefix string) (crypto.PubKey, error) {
+	hrp, bz, err := bech32.DecodeAndConvert(pubKey)
+	if err != nil {
+		return nil, err
+	} else if hrp != prefix {
+		return nil, fmt.Errorf("invalid public key prefix %s, expected %s", hrp, prefix)
+	}
+
+	return cryptoAmino.PubKeyFromBytes(bz)
+}
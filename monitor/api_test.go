@@ -0,0 +1,537 @@
+package monitor_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	"github.com/alexanderbez/titan/monitor"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	staketypes "github.com/cosmos/cosmos-sdk/x/stake/types"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/bech32"
+)
+
+func newTestGatewayBlock(height int64, blockTime time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"block": map[string]interface{}{
+			"header": map[string]interface{}{
+				"chain_id": "test-chain",
+				"height":   fmt.Sprintf("%d", height),
+				"time":     blockTime,
+			},
+			"data":     map[string]interface{}{"txs": []string{}},
+			"evidence": map[string]interface{}{"evidence": []interface{}{}},
+		},
+	}
+}
+
+func newTestGatewayServer(t *testing.T, responses map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		raw, err := json.Marshal(res)
+		require.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+}
+
+func TestGatewayJailedValidators(t *testing.T) {
+	codec := newStakingTestCodec()
+
+	opAddr1, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	// the network uses custom Bech32 prefixes
+	valAddr1, err := bech32.ConvertAndEncode("osmovaloper", opAddr1.Bytes())
+	require.NoError(t, err)
+
+	pubKey := ed25519.GenPrivKey().PubKey().(ed25519.PubKeyEd25519)
+
+	validators := map[string]interface{}{
+		"validators": []interface{}{
+			map[string]interface{}{
+				"operator_address": valAddr1,
+				"consensus_pubkey": map[string]interface{}{
+					"@type": "/cosmos.crypto.ed25519.PubKey",
+					"key":   pubKey[:],
+				},
+				"jailed":           true,
+				"status":           "BOND_STATUS_UNBONDING",
+				"tokens":           "1000",
+				"delegator_shares": "1000.000000000000000000",
+				"description":      map[string]interface{}{"moniker": "test"},
+				"commission": map[string]interface{}{
+					"commission_rates": map[string]interface{}{
+						"rate":            "0.100000000000000000",
+						"max_rate":        "0.200000000000000000",
+						"max_change_rate": "0.010000000000000000",
+					},
+				},
+			},
+		},
+	}
+
+	ts := newTestGatewayServer(t, map[string]interface{}{
+		"/cosmos/staking/v1beta1/validators": validators,
+	})
+	defer ts.Close()
+
+	cfg := config.Config{
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Operator: valAddr1},
+			},
+		},
+		Network: config.NetworkConfig{
+			Clients: []string{ts.URL},
+			API:     config.APIGateway,
+			Bech32:  config.Bech32{AccAddr: "osmo", ValAddr: "osmovaloper", ConsPub: "osmovalconspub"},
+		},
+	}
+
+	jvm := newTestJailedValidatorMonitor(t, cfg)
+	resp, id, err := jvm.Exec()
+	require.NoError(t, err)
+
+	var vals []staketypes.BechValidator
+	err = codec.UnmarshalJSON(resp, &vals)
+	require.NoError(t, err)
+
+	rawHash := sha256.Sum256(resp)
+	exID := rawHash[:]
+
+	bechPubKey, err := bech32.ConvertAndEncode("osmovalconspub", pubKey.Bytes())
+	require.NoError(t, err)
+
+	require.Equal(t, exID, id)
+	require.Len(t, vals, 1)
+	require.Equal(t, opAddr1, vals[0].Owner)
+	require.Equal(t, bechPubKey, vals[0].PubKey)
+	require.Equal(t, sdk.Unbonding, vals[0].Status)
+	require.True(t, vals[0].Revoked)
+	require.Equal(t, "test", vals[0].Description.Moniker)
+}
+
+func TestGatewayUpgradeCountdowns(t *testing.T) {
+	codec := newGovTestCodec()
+
+	// the chain is at height 200 with a block time of five seconds
+	now := time.Now().UTC()
+
+	proposals := map[string]interface{}{
+		"proposals": []interface{}{
+			map[string]interface{}{
+				"id": "1",
+				"messages": []interface{}{
+					map[string]interface{}{
+						"@type": "/cosmos.upgrade.v1beta1.MsgSoftwareUpgrade",
+						"plan":  map[string]interface{}{"name": "v1.0.0", "height": "300"},
+					},
+				},
+				"status":           "PROPOSAL_STATUS_PASSED",
+				"deposit_end_time": now.Add(-time.Hour),
+				"voting_end_time":  now.Add(-time.Minute),
+				"title":            "test upgrade proposal",
+				"summary":          "upgrade without a height in the description",
+			},
+		},
+	}
+
+	ts := newTestGatewayServer(t, map[string]interface{}{
+		"/cosmos/gov/v1/proposals":                      proposals,
+		"/cosmos/base/tendermint/v1beta1/blocks/latest": newTestGatewayBlock(200, now),
+		"/cosmos/base/tendermint/v1beta1/blocks/100":    newTestGatewayBlock(100, now.Add(-500*time.Second)),
+	})
+	defer ts.Close()
+
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	cfg := config.Config{
+		Network: config.NetworkConfig{Clients: []string{ts.URL}, API: config.APIGateway},
+		Governance: config.Governance{
+			UpgradeCountdowns: []uint{24, 6, 1},
+		},
+	}
+
+	gum := monitor.NewGovUpgradeMonitor(
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.GovUpgradeMonitorName, monitor.GovUpgradeMonitorMemo,
+	)
+
+	resp, id, err := gum.Exec()
+	require.NoError(t, err)
+
+	var countdowns []monitor.UpgradeCountdown
+	err = codec.UnmarshalJSON(resp, &countdowns)
+	require.NoError(t, err)
+
+	rawHash := sha256.Sum256(resp)
+	exID := rawHash[:]
+
+	require.Equal(t, exID, id)
+	require.Len(t, countdowns, 1)
+	require.Equal(t, "v1.0.0", countdowns[0].Name)
+	require.Equal(t, int64(300), countdowns[0].Height)
+	require.Equal(t, time.Hour.String(), countdowns[0].Countdown)
+}
+
+func TestGatewayProposalStatus(t *testing.T) {
+	var query string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("proposal_status")
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"proposals":[{"id":"1","status":"PROPOSAL_STATUS_VOTING_PERIOD","title":"test"}]}`))
+	}))
+	defer ts.Close()
+
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	cfg := config.Config{
+		Network: config.NetworkConfig{Clients: []string{ts.URL}, API: config.APIGateway},
+	}
+
+	gvm := monitor.NewGovVotingMonitor(
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.GovVotingMonitorName, monitor.GovVotingMonitorMemo,
+	)
+
+	resp, id, err := gvm.Exec()
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.NotNil(t, id)
+	require.Equal(t, "PROPOSAL_STATUS_VOTING_PERIOD", query)
+}
+
+func TestGatewaySelfDelegationStatus(t *testing.T) {
+	opAddr1, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	valAddr1, err := bech32.ConvertAndEncode("cosmosvaloper", opAddr1.Bytes())
+	require.NoError(t, err)
+
+	pubKey := ed25519.GenPrivKey().PubKey().(ed25519.PubKeyEd25519)
+
+	validators, err := json.Marshal(map[string]interface{}{
+		"validators": []interface{}{
+			map[string]interface{}{
+				"operator_address": valAddr1,
+				"consensus_pubkey": map[string]interface{}{
+					"@type": "/cosmos.crypto.ed25519.PubKey",
+					"key":   pubKey[:],
+				},
+				"status":           "BOND_STATUS_BONDED",
+				"tokens":           "10000",
+				"delegator_shares": "10000.000000000000000000",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// newServer returns a server responding to the self-delegation query with a
+	// given status and body
+	newServer := func(status int, body string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/cosmos/staking/v1beta1/validators" {
+				w.WriteHeader(http.StatusOK)
+				w.Write(validators)
+				return
+			}
+
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}))
+	}
+
+	newMonitor := func(ts *httptest.Server) *monitor.SelfDelegationMonitor {
+		logger, err := core.CreateBaseLogger("", false)
+		require.NoError(t, err)
+
+		cfg := config.Config{
+			Filters: config.Filters{
+				Validators: []config.ValidatorFilter{
+					config.ValidatorFilter{Operator: valAddr1},
+				},
+			},
+			Network: config.NetworkConfig{Clients: []string{ts.URL}, API: config.APIGateway},
+			Staking: config.Staking{MinSelfDelegation: 1000},
+		}
+
+		return monitor.NewSelfDelegationMonitor(
+			logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.SelfDelegationMonitorName, monitor.SelfDelegationMonitorMemo,
+		)
+	}
+
+	// an error response must not be mistaken for a missing delegation
+	failing := newServer(http.StatusInternalServerError, `{"code":13,"message":"internal error"}`)
+	defer failing.Close()
+
+	resp, id, err := newMonitor(failing).Exec()
+	require.Error(t, err)
+	require.Nil(t, resp)
+	require.Nil(t, id)
+
+	// a missing delegation has no shares
+	missing := newServer(http.StatusNotFound, `{"code":5,"message":"delegation not found"}`)
+	defer missing.Close()
+
+	resp, id, err = newMonitor(missing).Exec()
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.NotNil(t, id)
+}
+
+func TestGatewayPagination(t *testing.T) {
+	codec := newGovTestCodec()
+
+	// the chain is at height 200 with a block time of five seconds
+	now := time.Now().UTC()
+
+	var valAddrs, voters []string
+	for i := byte(1); i <= 3; i++ {
+		accAddr := sdk.AccAddress(bytes.Repeat([]byte{i}, 20))
+
+		valAddr, err := bech32.ConvertAndEncode("cosmosvaloper", accAddr.Bytes())
+		require.NoError(t, err)
+
+		voter, err := bech32.ConvertAndEncode("cosmos", accAddr.Bytes())
+		require.NoError(t, err)
+
+		valAddrs = append(valAddrs, valAddr)
+		voters = append(voters, voter)
+	}
+
+	newVotes := func(voter, nextKey string) map[string]interface{} {
+		return map[string]interface{}{
+			"votes": []interface{}{
+				map[string]interface{}{
+					"proposal_id": "1",
+					"voter":       voter,
+					"options":     []interface{}{map[string]interface{}{"option": "VOTE_OPTION_YES"}},
+				},
+			},
+			"pagination": map[string]interface{}{"next_key": nextKey},
+		}
+	}
+
+	// the proposal is on the second page of proposals and the first two
+	// validators voted on different pages of votes
+	pages := map[string]map[string]interface{}{
+		"/cosmos/gov/v1/proposals": {
+			"": map[string]interface{}{
+				"proposals":  []interface{}{},
+				"pagination": map[string]interface{}{"next_key": "Ag=="},
+			},
+			"Ag==": map[string]interface{}{
+				"proposals": []interface{}{
+					map[string]interface{}{
+						"id":              "1",
+						"status":          "PROPOSAL_STATUS_VOTING_PERIOD",
+						"voting_end_time": now.Add(time.Hour),
+						"title":           "test text proposal",
+					},
+				},
+				"pagination": map[string]interface{}{"next_key": nil},
+			},
+		},
+		"/cosmos/gov/v1/proposals/1/votes": {
+			"":     newVotes(voters[0], "AQ=="),
+			"AQ==": newVotes(voters[1], ""),
+		},
+	}
+
+	responses := map[string]interface{}{
+		"/cosmos/base/tendermint/v1beta1/blocks/latest": newTestGatewayBlock(200, now),
+		"/cosmos/base/tendermint/v1beta1/blocks/100":    newTestGatewayBlock(100, now.Add(-500*time.Second)),
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, ok := responses[r.URL.Path]
+		if page, isPaginated := pages[r.URL.Path]; isPaginated {
+			res, ok = page[r.URL.Query().Get("pagination.key")]
+		}
+
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		raw, err := json.Marshal(res)
+		require.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+	defer ts.Close()
+
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	cfg := config.Config{
+		Network: config.NetworkConfig{Clients: []string{ts.URL}, API: config.APIGateway},
+		Governance: config.Governance{
+			VoteReminders: []uint{2},
+		},
+	}
+
+	for _, valAddr := range valAddrs {
+		cfg.Filters.Validators = append(cfg.Filters.Validators, config.ValidatorFilter{Operator: valAddr})
+	}
+
+	gvrm := monitor.NewGovVoteReminderMonitor(
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.GovVoteReminderMonitorName, monitor.GovVoteReminderMonitorMemo,
+	)
+
+	resp, _, err := gvrm.Exec()
+	require.NoError(t, err)
+
+	var missingVotes []monitor.MissingVote
+	err = codec.UnmarshalJSON(resp, &missingVotes)
+	require.NoError(t, err)
+
+	require.Len(t, missingVotes, 1)
+	require.Equal(t, int64(1), missingVotes[0].ProposalID)
+	require.Equal(t, []string{valAddrs[2]}, missingVotes[0].Validators)
+}
+
+func TestGatewayUndelegations(t *testing.T) {
+	codec := newStakingTestCodec()
+
+	delegator, err := bech32.ConvertAndEncode("cosmos", bytes.Repeat([]byte{1}, 20))
+	require.NoError(t, err)
+
+	valAddr1, err := bech32.ConvertAndEncode("cosmosvaloper", bytes.Repeat([]byte{2}, 20))
+	require.NoError(t, err)
+
+	valAddr2, err := bech32.ConvertAndEncode("cosmosvaloper", bytes.Repeat([]byte{3}, 20))
+	require.NoError(t, err)
+
+	pubKey := ed25519.GenPrivKey().PubKey().(ed25519.PubKeyEd25519)
+
+	// the validator has 100000 tokens before both undelegations
+	validators := map[string]interface{}{
+		"validators": []interface{}{
+			map[string]interface{}{
+				"operator_address": valAddr1,
+				"consensus_pubkey": map[string]interface{}{
+					"@type": "/cosmos.crypto.ed25519.PubKey",
+					"key":   pubKey[:],
+				},
+				"status":           "BOND_STATUS_BONDED",
+				"tokens":           "78000",
+				"delegator_shares": "39000.000000000000000000",
+			},
+		},
+	}
+
+	newTxs := func(hash string, height int64, msg map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"tx_responses": []interface{}{
+				map[string]interface{}{
+					"height": fmt.Sprintf("%d", height),
+					"txhash": hash,
+					"code":   0,
+					"tx": map[string]interface{}{
+						"body": map[string]interface{}{"messages": []interface{}{msg}},
+					},
+				},
+			},
+		}
+	}
+
+	amount := func(tokens string) map[string]interface{} {
+		return map[string]interface{}{"denom": "uatom", "amount": tokens}
+	}
+
+	// 2000 tokens (2.5%) unbonded and 20000 tokens (20.4%) redelegated
+	searches := map[string]interface{}{
+		"unbond.validator": newTxs("01", 10, map[string]interface{}{
+			"@type":             "/cosmos.staking.v1beta1.MsgUndelegate",
+			"delegator_address": delegator,
+			"validator_address": valAddr1,
+			"amount":            amount("2000"),
+		}),
+		"redelegate.source_validator": newTxs("02", 11, map[string]interface{}{
+			"@type":                 "/cosmos.staking.v1beta1.MsgBeginRedelegate",
+			"delegator_address":     delegator,
+			"validator_src_address": valAddr1,
+			"validator_dst_address": valAddr2,
+			"amount":                amount("20000"),
+		}),
+	}
+
+	responses := map[string]interface{}{
+		"/cosmos/staking/v1beta1/validators":            validators,
+		"/cosmos/base/tendermint/v1beta1/blocks/latest": newTestGatewayBlock(9, time.Now().UTC()),
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, ok := responses[r.URL.Path]
+		if r.URL.Path == "/cosmos/tx/v1beta1/txs" {
+			query := r.URL.Query().Get("query")
+			require.Equal(t, query, r.URL.Query().Get("events"))
+
+			res, ok = searches[strings.TrimSuffix(query, fmt.Sprintf("='%s'", valAddr1))]
+		}
+
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		raw, err := json.Marshal(res)
+		require.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+	defer ts.Close()
+
+	cfg := config.Config{
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Operator: valAddr1},
+			},
+		},
+		Network: config.NetworkConfig{Clients: []string{ts.URL}, API: config.APIGateway},
+		Staking: config.Staking{MaxUndelegationShare: 10},
+	}
+
+	udm := newTestUndelegationMonitor(t, cfg)
+
+	// the first execution starts at the latest block
+	_, _, err = udm.Exec()
+	require.Error(t, err)
+
+	resp, _, err := udm.Exec()
+	require.NoError(t, err)
+
+	var undelegations []monitor.Undelegation
+	err = codec.UnmarshalJSON(resp, &undelegations)
+	require.NoError(t, err)
+
+	require.Len(t, undelegations, 1)
+	require.Equal(t, "02", undelegations[0].TxHash)
+	require.Equal(t, monitor.UndelegationTypeRedelegation, undelegations[0].Type)
+	require.Equal(t, "20.41%", undelegations[0].Share)
+	require.True(t, undelegations[0].Tokens.Equal(sdk.NewRat(20000)))
+	require.True(t, undelegations[0].Shares.Equal(sdk.NewRat(10000)))
+}
@@ -0,0 +1,182 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/wire"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+)
+
+var (
+	_ Monitor = (*LowBalanceMonitor)(nil)
+)
+
+// Bank monitor alert related constants.
+const (
+	LowBalanceMonitorMemo = "Accounts With Low Balances"
+	LowBalanceMonitorName = "bank/lowBalance"
+)
+
+type (
+	baseBankMonitor struct {
+		codec  *wire.Codec
+		api    chainAPI
+		logger core.Logger
+		cm     *core.ClientManager
+		name   string
+		memo   string
+	}
+
+	// accountFilter defines a parsed account filter.
+	accountFilter struct {
+		address    string
+		minBalance sdk.Coins
+	}
+
+	// LowBalance defines a structure for containing an account whose balance of
+	// one or more denominations has fallen below the configured minimum.
+	LowBalance struct {
+		Address    string    `json:"address"`
+		Balance    sdk.Coins `json:"balance"`
+		MinBalance sdk.Coins `json:"min_balance"`
+		LowDenoms  []string  `json:"low_denoms"`
+	}
+)
+
+func newBaseBankMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *baseBankMonitor {
+	logger = logger.With("module", name)
+
+	codec := wire.NewCodec()
+	auth.RegisterWire(codec)
+	wire.RegisterCrypto(codec)
+
+	return &baseBankMonitor{
+		codec:  codec,
+		api:    deps.api,
+		logger: logger,
+		cm:     deps.Clients,
+		name:   name,
+		memo:   memo,
+	}
+}
+
+// Name implements the Monitor interface. It returns the monitor's name.
+func (bm *baseBankMonitor) Name() string { return bm.name }
+
+// Memo implements the Monitor interface. It returns the monitor's memo.
+func (bm *baseBankMonitor) Memo() string { return bm.memo }
+
+// getBalance returns the coins owned by an account from a given client.
+func (bm baseBankMonitor) getBalance(client, address string) (sdk.Coins, error) {
+	path, err := bm.api.BalancePath(address)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := fetchPages(bm.api, client, path, func(url string) ([]byte, error) {
+		return bm.cm.Request(url, core.RequestGET, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	coins := sdk.Coins{}
+	for _, page := range pages {
+		pageCoins, err := bm.api.DecodeBalance(page)
+		if err != nil {
+			return nil, err
+		}
+
+		coins = coins.Plus(pageCoins)
+	}
+
+	return coins, nil
+}
+
+// LowBalanceMonitor defines a monitor responsible for monitoring when the
+// balance of filtered accounts falls below a configured minimum.
+type LowBalanceMonitor struct {
+	*baseBankMonitor
+	filter []accountFilter
+}
+
+// NewLowBalanceMonitor returns a reference to a new LowBalanceMonitor. The
+// configuration is assumed to have been validated.
+func NewLowBalanceMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *LowBalanceMonitor {
+	filter := make([]accountFilter, len(cfg.Filters.Accounts))
+	for i, accountFilter := range cfg.Filters.Accounts {
+		minBalance, _ := sdk.ParseCoins(accountFilter.MinBalance)
+
+		filter[i].address = accountFilter.Address
+		filter[i].minBalance = minBalance
+	}
+
+	return &LowBalanceMonitor{
+		baseBankMonitor: newBaseBankMonitor(logger, cfg, deps, name, memo),
+		filter:          filter,
+	}
+}
+
+// Exec implements the Monitor interface. It attempts to fetch the balance of
+// each filtered account and compare it against the account's minimum balance.
+// Upon success, the serialized encoding of accounts with low balances will be
+// returned along with an ID that is the SHA256 of each account address and low
+// denomination so that a changing balance does not re-trigger an alert. An
+// error is returned otherwise.
+func (lbm *LowBalanceMonitor) Exec() (resp, id []byte, err error) {
+	client := lbm.cm.Next()
+	lbm.logger.Info("monitoring for accounts with low balances")
+
+	var (
+		lowBalances []LowBalance
+		idParts     []string
+	)
+
+	for _, accountFilter := range lbm.filter {
+		balance, err := lbm.getBalance(client, accountFilter.address)
+		if err != nil {
+			lbm.logger.Errorf("failed to get balance for account %s: %v", accountFilter.address, err)
+			return nil, nil, errors.Wrap(err, "failed to get account balance")
+		}
+
+		var lowDenoms []string
+		for _, minCoin := range accountFilter.minBalance {
+			if balance.AmountOf(minCoin.Denom).LT(minCoin.Amount) {
+				lowDenoms = append(lowDenoms, minCoin.Denom)
+			}
+		}
+
+		if len(lowDenoms) != 0 {
+			lowBalances = append(lowBalances, LowBalance{
+				Address:    accountFilter.address,
+				Balance:    balance,
+				MinBalance: accountFilter.minBalance,
+				LowDenoms:  lowDenoms,
+			})
+
+			idParts = append(idParts, fmt.Sprintf("%s/%s", accountFilter.address, strings.Join(lowDenoms, ",")))
+		}
+	}
+
+	if len(lowBalances) == 0 {
+		return nil, nil, errors.New("no accounts matching filter with low balances")
+	}
+
+	raw, err := wire.MarshalJSONIndent(lbm.codec, lowBalances)
+	if err != nil {
+		lbm.logger.Errorf("failed to serialize low balances: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to serialize low balances")
+	}
+
+	rawHash := sha256.Sum256([]byte(strings.Join(idParts, ";")))
+	id = rawHash[:]
+
+	return raw, id, nil
+}
@@ -0,0 +1,121 @@
+package monitor_test
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	"github.com/alexanderbez/titan/monitor"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/wire"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newBankTestCodec() *wire.Codec {
+	codec := wire.NewCodec()
+	auth.RegisterWire(codec)
+	wire.RegisterCrypto(codec)
+
+	return codec
+}
+
+func newTestLowBalanceMonitor(t *testing.T, ts *httptest.Server, address, minBalance string) *monitor.LowBalanceMonitor {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	clients := []string{ts.URL}
+	cfg := config.Config{
+		Filters: config.Filters{
+			Accounts: []config.AccountFilter{
+				config.AccountFilter{Address: address, MinBalance: minBalance},
+			},
+		},
+		Network: config.NetworkConfig{Clients: clients},
+	}
+
+	return monitor.NewLowBalanceMonitor(
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.LowBalanceMonitorName, monitor.LowBalanceMonitorMemo,
+	)
+}
+
+func newTestAccountServer(t *testing.T, account auth.Account) *httptest.Server {
+	codec := newBankTestCodec()
+
+	raw, err := codec.MarshalJSON(account)
+	require.NoError(t, err)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+}
+
+func TestNoLowBalances(t *testing.T) {
+	addr, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	account := &auth.BaseAccount{
+		Address: addr,
+		Coins:   sdk.Coins{sdk.NewInt64Coin("steak", 100)},
+	}
+
+	ts := newTestAccountServer(t, account)
+	defer ts.Close()
+
+	lbm := newTestLowBalanceMonitor(t, ts, addr.String(), "100steak")
+
+	resp, id, err := lbm.Exec()
+	require.Error(t, err)
+	require.Nil(t, resp)
+	require.Nil(t, id)
+}
+
+func TestLowBalances(t *testing.T) {
+	codec := newBankTestCodec()
+
+	addr, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	account := &auth.BaseAccount{
+		Address: addr,
+		Coins:   sdk.Coins{sdk.NewInt64Coin("steak", 100)},
+	}
+
+	ts := newTestAccountServer(t, account)
+	defer ts.Close()
+
+	lbm := newTestLowBalanceMonitor(t, ts, addr.String(), "10photino,150steak")
+
+	resp, id, err := lbm.Exec()
+	require.NoError(t, err)
+	require.NotNil(t, id)
+
+	var lowBalances []monitor.LowBalance
+	err = codec.UnmarshalJSON(resp, &lowBalances)
+	require.NoError(t, err)
+
+	require.Len(t, lowBalances, 1)
+	require.Equal(t, addr.String(), lowBalances[0].Address)
+	require.Equal(t, []string{"photino", "steak"}, lowBalances[0].LowDenoms)
+
+	// a changing balance for the same low denominations results in the same ID
+	account.Coins = sdk.Coins{sdk.NewInt64Coin("steak", 50)}
+
+	ts2 := newTestAccountServer(t, account)
+	defer ts2.Close()
+
+	lbm = newTestLowBalanceMonitor(t, ts2, addr.String(), "10photino,150steak")
+
+	resp2, id2, err := lbm.Exec()
+	require.NoError(t, err)
+	require.NotEqual(t, resp, resp2)
+	require.Equal(t, id, id2)
+
+	rawHash := sha256.Sum256(resp)
+	require.NotEqual(t, rawHash[:], id)
+}
@@ -0,0 +1,116 @@
+package monitor
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// blockCacheSize defines the maximum number of blocks at explicit heights
+// kept in the block cache.
+const blockCacheSize = 2 * maxBlockBackfill
+
+type (
+	// BlockCache implements a block cache shared by all monitors acting on
+	// blocks so that each block is fetched once per poll and all monitors act
+	// on the same block. The latest block is fetched from the next LCD client
+	// at most once per poll as started by BeginPoll. Blocks at explicit heights
+	// never change and are kept in a LRU cache. The height of the latest block
+	// is recorded as a metric.
+	BlockCache struct {
+		mu      sync.Mutex
+		api     chainAPI
+		cm      *core.ClientManager
+		chainID string
+
+		poll       uint64
+		latest     *ctypes.ResultBlock
+		latestPoll uint64
+
+		size   int
+		blocks map[int64]*list.Element
+		lru    *list.List
+	}
+
+	blockCacheEntry struct {
+		height int64
+		block  *ctypes.ResultBlock
+	}
+)
+
+// NewBlockCache returns a reference to a new BlockCache fetching blocks from
+// the given client manager.
+func NewBlockCache(cfg config.Config, cm *core.ClientManager) *BlockCache {
+	return &BlockCache{
+		api:     newChainAPI(cfg),
+		cm:      cm,
+		chainID: cfg.ChainID,
+		size:    blockCacheSize,
+		blocks:  make(map[int64]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// BeginPoll starts a given poll, after which the latest block is fetched again.
+// Starting the current poll again has no effect, so all monitors sharing the
+// cache may start the same poll.
+func (bc *BlockCache) BeginPoll(poll uint64) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	bc.poll = poll
+}
+
+// Latest returns the latest block. The block is fetched from the next LCD
+// client unless it has been fetched within the current poll.
+func (bc *BlockCache) Latest() (*ctypes.ResultBlock, error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if bc.latest != nil && bc.latestPoll == bc.poll {
+		return bc.latest, nil
+	}
+
+	block, err := fetchBlock(bc.cm, bc.api, bc.cm.Next())
+	if err != nil {
+		return nil, err
+	}
+
+	core.DefaultMetrics().ObserveLatestHeight(bc.chainID, block.Block.Height)
+
+	bc.latest = block
+	bc.latestPoll = bc.poll
+
+	return block, nil
+}
+
+// At returns the block at a given height. The block is fetched from the next
+// LCD client, or the quorum of clients if set, unless it is cached.
+func (bc *BlockCache) At(height int64) (*ctypes.ResultBlock, error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if elem, ok := bc.blocks[height]; ok {
+		bc.lru.MoveToFront(elem)
+		return elem.Value.(*blockCacheEntry).block, nil
+	}
+
+	block, err := fetchBlockAt(bc.cm, bc.api, bc.cm.Next(), height)
+	if err != nil {
+		return nil, err
+	}
+
+	bc.blocks[height] = bc.lru.PushFront(&blockCacheEntry{height: height, block: block})
+
+	if bc.lru.Len() > bc.size {
+		oldest := bc.lru.Back()
+		bc.lru.Remove(oldest)
+		delete(bc.blocks, oldest.Value.(*blockCacheEntry).height)
+	}
+
+	return block, nil
+}
@@ -0,0 +1,186 @@
+package monitor_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	"github.com/alexanderbez/titan/monitor"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func TestSharedLatestBlock(t *testing.T) {
+	codec := newSlashingTestCodec()
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	pubKey := ed25519.GenPrivKey().PubKey()
+
+	var (
+		height   int64 = 1
+		requests int32
+	)
+
+	// every request returns a newer block
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		block := &ctypes.ResultBlock{
+			Block: tmtypes.MakeBlock(atomic.AddInt64(&height, 1), nil, &tmtypes.Commit{}, nil),
+		}
+
+		raw, err := codec.MarshalJSON(block)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+	defer ts.Close()
+
+	cfg := config.Config{
+		Monitors: []string{config.MonitorMissingSignatures, config.MonitorDoubleSigning},
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Address: pubKey.Address().String()},
+			},
+		},
+		Network: config.NetworkConfig{Clients: []string{ts.URL}},
+	}
+
+	clients := monitor.NewLCDClientManager(cfg, logger)
+	defer clients.Stop()
+
+	monitors := monitor.CreateMonitors(cfg, logger, clients, nil)
+	require.Len(t, monitors, 2)
+
+	resp, _, err := monitors[0].Exec()
+	require.NoError(t, err)
+
+	var missingSigners monitor.MissingSigners
+	require.NoError(t, codec.UnmarshalJSON(resp, &missingSigners))
+	require.Equal(t, int64(1), missingSigners.Height)
+
+	// the second monitor acts on the same block without fetching it again
+	_, _, err = monitors[1].Exec()
+	require.Error(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// the block is not acted upon twice within the same poll
+	_, _, err = monitors[0].Exec()
+	require.Error(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// the latest block is fetched once again in the next poll
+	for _, mon := range monitors {
+		mon.(monitor.PollObserver).BeginPoll(1)
+	}
+
+	resp, _, err = monitors[0].Exec()
+	require.NoError(t, err)
+	require.NoError(t, codec.UnmarshalJSON(resp, &missingSigners))
+	require.Equal(t, int64(2), missingSigners.Height)
+
+	_, _, err = monitors[1].Exec()
+	require.Error(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestBlockCacheAt(t *testing.T) {
+	codec := newSlashingTestCodec()
+
+	var mu sync.Mutex
+	requests := make(map[string]int)
+
+	requestsOf := func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return requests[path]
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+
+		var height int64
+		if _, err := fmt.Sscanf(r.URL.Path, "/blocks/%d", &height); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		raw, err := codec.MarshalJSON(&ctypes.ResultBlock{
+			Block: tmtypes.MakeBlock(height, nil, &tmtypes.Commit{}, nil),
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+	defer ts.Close()
+
+	cfg := config.Config{Network: config.NetworkConfig{Clients: []string{ts.URL}}}
+	blocks := monitor.NewBlockCache(cfg, core.NewClientManager(cfg.Network.Clients))
+
+	for i := 0; i < 2; i++ {
+		block, err := blocks.At(1)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), block.Block.Height)
+	}
+
+	require.Equal(t, 1, requestsOf("/blocks/1"))
+
+	// the least recently used block is evicted once the cache is full
+	for height := int64(2); height <= 1000; height++ {
+		_, err := blocks.At(height)
+		require.NoError(t, err)
+	}
+
+	_, err := blocks.At(1000)
+	require.NoError(t, err)
+	require.Equal(t, 1, requestsOf("/blocks/1000"))
+
+	_, err = blocks.At(1)
+	require.NoError(t, err)
+	require.Equal(t, 2, requestsOf("/blocks/1"))
+}
+
+func TestLatestBlockHeightMetric(t *testing.T) {
+	ts1 := newTestBlockServer(t, &ctypes.ResultBlock{
+		Block: tmtypes.MakeBlock(10, nil, &tmtypes.Commit{}, nil),
+	})
+	defer ts1.Close()
+
+	ts2 := newTestBlockServer(t, &ctypes.ResultBlock{
+		Block: tmtypes.MakeBlock(5, nil, &tmtypes.Commit{}, nil),
+	})
+	defer ts2.Close()
+
+	cfg := config.Config{ChainID: "latest-height-chain"}
+
+	// every source of the chain's latest block records its height
+	for _, client := range []string{ts1.URL, ts2.URL} {
+		blocks := monitor.NewBlockCache(cfg, core.NewClientManager([]string{client}))
+
+		_, err := blocks.Latest()
+		require.NoError(t, err)
+	}
+
+	// the source that is behind does not move the observed height backwards
+	require.Contains(t, scrapeMetrics(t), `titan_chain_latest_block_height{chain_id="latest-height-chain"} 10`)
+}
@@ -0,0 +1,358 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	"github.com/cosmos/cosmos-sdk/wire"
+	staketypes "github.com/cosmos/cosmos-sdk/x/stake/types"
+
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
+)
+
+// blockTimeSampleSize defines the number of trailing blocks used to estimate
+// the network's average block time.
+const blockTimeSampleSize = 100
+
+// NewLCDClientManager returns a reference to a new ClientManager of the
+// configured LCD clients with the configured quorum. If a health check
+// interval is configured, the clients are health checked against their latest
+// block until the manager is stopped. If an expected chain ID or genesis hash
+// is configured, the clients are verified to serve the expected chain before
+// returning and then every verify interval if configured. Requests to each
+// client are limited to the configured rate.
+func NewLCDClientManager(cfg config.Config, logger core.Logger) *core.ClientManager {
+	cm := core.NewClientManager(cfg.Network.Clients)
+	cm.SetQuorum(int(cfg.Network.Quorum))
+	cm.SetRateLimit(cfg.Network.RateLimit, int(cfg.Network.RateBurst))
+
+	api := newChainAPI(cfg)
+
+	if cfg.VerifyClients() {
+		// the genesis hash is validated to be HEX encoded
+		genesisHash, _ := hex.DecodeString(cfg.Network.GenesisHash)
+
+		interval := time.Duration(cfg.Network.VerifyInterval) * time.Second
+		cm.StartVerification(verifyClient(cm, api, cfg.Network.ExpectedChainID, genesisHash), interval, logger.With("module", "clients"))
+	}
+
+	if cfg.Network.HealthCheckInterval != 0 {
+		check := func(client string) (int64, error) {
+			block, err := fetchClientBlock(cm, api, client, 0)
+			if err != nil {
+				return 0, err
+			}
+
+			return block.Block.Height, nil
+		}
+
+		interval := time.Duration(cfg.Network.HealthCheckInterval) * time.Second
+		cm.StartHealthChecks(check, interval, cfg.Network.MaxHeightLag, logger.With("module", "clients"))
+	}
+
+	return cm
+}
+
+// NewRPCClientManager returns a reference to a new ClientManager of the
+// configured Tendermint RPC clients. If an expected chain ID or genesis hash is
+// configured, the clients are verified to serve the expected chain similar to
+// the LCD clients of NewLCDClientManager.
+func NewRPCClientManager(cfg config.Config, logger core.Logger) *core.ClientManager {
+	cm := core.NewClientManager(cfg.Network.RPCClients)
+
+	if cfg.VerifyClients() {
+		// the genesis hash is validated to be HEX encoded
+		genesisHash, _ := hex.DecodeString(cfg.Network.GenesisHash)
+
+		interval := time.Duration(cfg.Network.VerifyInterval) * time.Second
+		cm.StartVerification(verifyRPCClient(cm, cfg.Network.ExpectedChainID, genesisHash), interval, logger.With("module", "rpc-clients"))
+	}
+
+	return cm
+}
+
+// verifyClient returns a function verifying that a client's node runs a chain
+// with the given chain ID and, if a genesis hash is given, that the block at
+// height one has the given hash. An empty chain ID is not verified. Each
+// client is verified on its own through the given client manager without
+// failing over to others.
+func verifyClient(cm *core.ClientManager, api chainAPI, chainID string, genesisHash []byte) core.VerifyFunc {
+	return func(client string) (string, error) {
+		if chainID != "" {
+			resp, err := cm.RequestClient(client+api.NodeInfoPath(), core.RequestGET, nil)
+			if err != nil {
+				return "", errors.Wrap(err, "failed to get node info")
+			}
+
+			nodeChainID, err := api.DecodeChainID(resp)
+			if err != nil {
+				return "", errors.Wrap(err, "failed to decode node info")
+			}
+
+			if nodeChainID != chainID {
+				return fmt.Sprintf("chain ID %s does not match expected chain ID %s", nodeChainID, chainID), nil
+			}
+		}
+
+		if len(genesisHash) != 0 {
+			block, err := fetchClientBlock(cm, api, client, 1)
+			if err != nil {
+				return "", errors.Wrap(err, "failed to get genesis block")
+			}
+
+			if block.BlockMeta == nil || len(block.BlockMeta.BlockID.Hash) == 0 {
+				return "", errors.New("received genesis block without hash")
+			}
+
+			if hash := block.BlockMeta.BlockID.Hash; !bytes.Equal(hash, genesisHash) {
+				return fmt.Sprintf("genesis hash %X does not match expected genesis hash %X", []byte(hash), genesisHash), nil
+			}
+		}
+
+		return "", nil
+	}
+}
+
+// verifyRPCClient returns a function verifying a Tendermint RPC client similar
+// to verifyClient through the client's status and block endpoints.
+func verifyRPCClient(cm *core.ClientManager, chainID string, genesisHash []byte) core.VerifyFunc {
+	codec := wire.NewCodec()
+	ctypes.RegisterAmino(codec)
+
+	return func(client string) (string, error) {
+		if chainID != "" {
+			result, err := fetchRPCResult(cm, client+"/status")
+			if err != nil {
+				return "", errors.Wrap(err, "failed to get status")
+			}
+
+			var status ctypes.ResultStatus
+			if err := codec.UnmarshalJSON(result, &status); err != nil {
+				return "", errors.Wrap(err, "failed to decode status")
+			}
+
+			if network := status.NodeInfo.Network; network != chainID {
+				return fmt.Sprintf("chain ID %s does not match expected chain ID %s", network, chainID), nil
+			}
+		}
+
+		if len(genesisHash) != 0 {
+			result, err := fetchRPCResult(cm, client+"/block?height=1")
+			if err != nil {
+				return "", errors.Wrap(err, "failed to get genesis block")
+			}
+
+			var block ctypes.ResultBlock
+			if err := codec.UnmarshalJSON(result, &block); err != nil {
+				return "", errors.Wrap(err, "failed to decode genesis block")
+			}
+
+			if block.BlockMeta == nil || len(block.BlockMeta.BlockID.Hash) == 0 {
+				return "", errors.New("received genesis block without hash")
+			}
+
+			if hash := block.BlockMeta.BlockID.Hash; !bytes.Equal(hash, genesisHash) {
+				return fmt.Sprintf("genesis hash %X does not match expected genesis hash %X", []byte(hash), genesisHash), nil
+			}
+		}
+
+		return "", nil
+	}
+}
+
+// fetchBlock attempts to fetch and decode the latest block from a given
+// client using the given client manager.
+func fetchBlock(cm *core.ClientManager, api chainAPI, client string) (*ctypes.ResultBlock, error) {
+	resp, err := cm.Request(client+api.BlockPath(0), core.RequestGET, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.DecodeBlock(resp)
+}
+
+// fetchClientBlock attempts to fetch and decode the block at a given height,
+// or the latest block if zero, from a given client without failing over to
+// other clients of the given client manager.
+func fetchClientBlock(cm *core.ClientManager, api chainAPI, client string, height int64) (*ctypes.ResultBlock, error) {
+	resp, err := cm.RequestClient(client+api.BlockPath(height), core.RequestGET, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.DecodeBlock(resp)
+}
+
+// fetchBlockAt attempts to fetch and decode the block at a given height from a
+// given client. If the client manager has a quorum set, the block is read from
+// the quorum of clients.
+func fetchBlockAt(cm *core.ClientManager, api chainAPI, client string, height int64) (*ctypes.ResultBlock, error) {
+	resp, err := cm.QuorumRequest(client+api.BlockPath(height), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.DecodeBlock(resp)
+}
+
+// fetchValidators attempts to fetch and decode all validators from a given
+// client using the given client manager. If the client manager has a quorum
+// set, each page of validators is read from the quorum of clients.
+func fetchValidators(cm *core.ClientManager, api chainAPI, client string) ([]staketypes.BechValidator, error) {
+	pages, err := fetchPages(api, client, api.ValidatorsPath(), func(url string) ([]byte, error) {
+		return cm.QuorumRequest(url, validatorsDigest(api))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var vals []staketypes.BechValidator
+	for _, page := range pages {
+		pageVals, err := api.DecodeValidators(page)
+		if err != nil {
+			return nil, err
+		}
+
+		vals = append(vals, pageVals...)
+	}
+
+	return vals, nil
+}
+
+// fetchPages attempts to fetch the raw response bodies of all pages of a
+// paginated path from a given client using the given request function.
+func fetchPages(api chainAPI, client, path string, request func(url string) ([]byte, error)) ([][]byte, error) {
+	var pages [][]byte
+
+	for pagePath := path; pagePath != ""; {
+		body, err := request(client + pagePath)
+		if err != nil {
+			return nil, err
+		}
+
+		pages = append(pages, body)
+
+		if pagePath, err = api.NextPagePath(path, body); err != nil {
+			return nil, err
+		}
+	}
+
+	return pages, nil
+}
+
+// fetchRPCResult attempts to fetch a JSON-RPC response from a given Tendermint
+// RPC URL of one of the given client manager's clients without failing over to
+// others. The raw result is returned if the response contains no error.
+func fetchRPCResult(cm *core.ClientManager, url string) (json.RawMessage, error) {
+	resp, err := cm.RequestClient(url, core.RequestGET, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var rpcResp rpctypes.RPCResponse
+	if err := json.Unmarshal(resp, &rpcResp); err != nil {
+		return nil, err
+	}
+
+	if rpcResp.Error != nil {
+		return nil, errors.Wrap(rpcResp.Error, "received RPC error")
+	}
+
+	return rpcResp.Result, nil
+}
+
+// validatorsDigest returns a digest function of validator responses for quorum
+// reads. Validators are compared by their operator, consensus public key and
+// the state monitors act on, so that a client misreporting a validator's state
+// never agrees with the majority.
+func validatorsDigest(api chainAPI) core.DigestFunc {
+	return func(body []byte) (string, error) {
+		vals, err := api.DecodeValidators(body)
+		if err != nil {
+			return "", err
+		}
+
+		keys := make([]string, len(vals))
+		for i, val := range vals {
+			keys[i] = fmt.Sprintf(
+				"%s/%s/%t/%d/%s/%s",
+				val.Owner, val.PubKey, val.Revoked, val.Status, val.Tokens, val.DelegatorShares,
+			)
+		}
+
+		sort.Strings(keys)
+		return strings.Join(keys, ";"), nil
+	}
+}
+
+// estimateBlockTime returns the latest block from a given client along with
+// the average block time over the last blockTimeSampleSize blocks. An error is
+// returned if either block cannot be fetched or there is not enough history.
+func estimateBlockTime(cm *core.ClientManager, api chainAPI, client string) (*ctypes.ResultBlock, time.Duration, error) {
+	latest, err := fetchBlock(cm, api, client)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to get latest block")
+	}
+
+	sampleHeight := latest.Block.Height - blockTimeSampleSize
+	if sampleHeight < 1 {
+		sampleHeight = 1
+	}
+
+	if sampleHeight >= latest.Block.Height {
+		return nil, 0, errors.New("not enough blocks to estimate block time")
+	}
+
+	sample, err := fetchBlockAt(cm, api, client, sampleHeight)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to get block at height %d", sampleHeight)
+	}
+
+	elapsed := latest.Block.Time.Sub(sample.Block.Time)
+	blockTime := elapsed / time.Duration(latest.Block.Height-sample.Block.Height)
+
+	if blockTime <= 0 {
+		return nil, 0, errors.New("invalid block times received")
+	}
+
+	return latest, blockTime, nil
+}
+
+// hoursToThresholds converts a list of hours into a list of durations sorted
+// in ascending order.
+func hoursToThresholds(hours []uint) []time.Duration {
+	thresholds := make([]time.Duration, len(hours))
+	for i, h := range hours {
+		thresholds[i] = time.Duration(h) * time.Hour
+	}
+
+	sort.Slice(thresholds, func(i, j int) bool { return thresholds[i] < thresholds[j] })
+	return thresholds
+}
+
+// crossedThreshold returns the smallest threshold the remaining time falls
+// within where thresholds are sorted in ascending order. False is returned if
+// no threshold has been crossed yet or the remaining time is negative.
+func crossedThreshold(remaining time.Duration, thresholds []time.Duration) (time.Duration, bool) {
+	if remaining < 0 {
+		return 0, false
+	}
+
+	for _, threshold := range thresholds {
+		if remaining <= threshold {
+			return threshold, true
+		}
+	}
+
+	return 0, false
+}
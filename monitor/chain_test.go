@@ -0,0 +1,326 @@
+package monitor_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	"github.com/alexanderbez/titan/monitor"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/cosmos-sdk/x/gov"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// newTestLatestBlockServer returns a test LCD server serving the latest block
+// at the height stored in the given pointer.
+func newTestLatestBlockServer(t *testing.T, height *int64) *httptest.Server {
+	codec := newSlashingTestCodec()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		block := ctypes.ResultBlock{
+			Block: tmtypes.MakeBlock(atomic.LoadInt64(height), nil, &tmtypes.Commit{}, nil),
+		}
+
+		raw, err := codec.MarshalJSON(block)
+		require.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+}
+
+func TestLCDClientFailover(t *testing.T) {
+	codec := newGovTestCodec()
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	proposals := []gov.Proposal{
+		&gov.TextProposal{
+			ProposalID:   1,
+			Title:        "test text proposal",
+			Description:  "test text proposal",
+			ProposalType: gov.ProposalTypeText,
+			Status:       gov.StatusDepositPeriod,
+			TallyResult:  gov.EmptyTallyResult(),
+		},
+	}
+
+	raw, err := codec.MarshalJSON(proposals)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+	defer ts.Close()
+
+	// a client that is down and a client failing with a server error
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	cfg := config.Config{
+		Monitors: []string{config.MonitorNewProposals},
+		Network:  config.NetworkConfig{Clients: []string{down.URL, failing.URL, ts.URL}},
+	}
+
+	clients := monitor.NewLCDClientManager(cfg, logger)
+	defer clients.Stop()
+
+	monitors := monitor.CreateMonitors(cfg, logger, clients, nil)
+	require.Len(t, monitors, 1)
+
+	// every execution fails over to the healthy client within the same poll
+	for i := 0; i < len(cfg.Network.Clients); i++ {
+		_, _, err := monitors[0].Exec()
+		require.NoError(t, err)
+	}
+}
+
+func TestLCDClientHealthChecks(t *testing.T) {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	height1, height2 := int64(100), int64(50)
+
+	ts1 := newTestLatestBlockServer(t, &height1)
+	defer ts1.Close()
+
+	ts2 := newTestLatestBlockServer(t, &height2)
+	defer ts2.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	cfg := config.Config{
+		Network: config.NetworkConfig{
+			Clients:             []string{ts1.URL, ts2.URL, down.URL},
+			HealthCheckInterval: 1,
+			MaxHeightLag:        10,
+		},
+	}
+
+	clients := monitor.NewLCDClientManager(cfg, logger)
+	defer clients.Stop()
+
+	// the lagging and unreachable clients are ejected
+	waitFor(t, func() bool { return len(clients.Healthy()) == 1 })
+	require.Equal(t, []string{ts1.URL}, clients.Healthy())
+
+	for i := 0; i < len(cfg.Network.Clients); i++ {
+		require.Equal(t, ts1.URL, clients.Next())
+	}
+
+	// the lagging client is re-admitted once it catches up
+	atomic.StoreInt64(&height2, 95)
+	waitFor(t, func() bool { return len(clients.Healthy()) == 2 })
+	require.Equal(t, []string{ts1.URL, ts2.URL}, clients.Healthy())
+}
+
+func TestLCDClientRetries(t *testing.T) {
+	codec := newGovTestCodec()
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	raw, err := codec.MarshalJSON([]gov.Proposal{})
+	require.NoError(t, err)
+
+	var attempts int32
+
+	// the client is unavailable for the first request and requires a token
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+	defer ts.Close()
+
+	httpClient, err := core.NewHTTPClient(config.HTTP{Retries: 1, RetryBackoff: 1, BearerToken: "token"})
+	require.NoError(t, err)
+
+	defaultClient := core.DefaultHTTPClient()
+	core.SetDefaultHTTPClient(httpClient)
+	defer core.SetDefaultHTTPClient(defaultClient)
+
+	cfg := config.Config{
+		Monitors: []string{config.MonitorNewProposals},
+		Network:  config.NetworkConfig{Clients: []string{ts.URL}},
+	}
+
+	monitors := monitor.CreateMonitors(cfg, logger, monitor.NewLCDClientManager(cfg, logger), nil)
+	require.Len(t, monitors, 1)
+
+	// no proposals exist but the request succeeded after a retry
+	_, _, err = monitors[0].Exec()
+	require.Error(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestLCDClientTimeout(t *testing.T) {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	hung := make(chan struct{})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hung
+	}))
+	defer ts.Close()
+	defer close(hung)
+
+	httpClient, err := core.NewHTTPClient(config.HTTP{Timeout: 1})
+	require.NoError(t, err)
+
+	defaultClient := core.DefaultHTTPClient()
+	core.SetDefaultHTTPClient(httpClient)
+	defer core.SetDefaultHTTPClient(defaultClient)
+
+	cfg := config.Config{
+		Monitors: []string{config.MonitorNewProposals},
+		Network:  config.NetworkConfig{Clients: []string{ts.URL}},
+	}
+
+	monitors := monitor.CreateMonitors(cfg, logger, monitor.NewLCDClientManager(cfg, logger), nil)
+	require.Len(t, monitors, 1)
+
+	// a hung client does not block the execution
+	start := time.Now()
+	_, _, err = monitors[0].Exec()
+	require.Error(t, err)
+	require.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestLCDClientThrottling(t *testing.T) {
+	codec := newGovTestCodec()
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	raw, err := codec.MarshalJSON([]gov.Proposal{})
+	require.NoError(t, err)
+
+	throttled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer throttled.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+	defer ts.Close()
+
+	cfg := config.Config{
+		Monitors: []string{config.MonitorNewProposals},
+		Network:  config.NetworkConfig{Clients: []string{throttled.URL, ts.URL}},
+	}
+
+	clients := monitor.NewLCDClientManager(cfg, logger)
+	defer clients.Stop()
+
+	monitors := monitor.CreateMonitors(cfg, logger, clients, nil)
+	require.Len(t, monitors, 1)
+
+	// no proposals exist but the request failed over to the other client
+	start := time.Now()
+	_, _, err = monitors[0].Exec()
+	require.Error(t, err)
+	require.True(t, time.Since(start) < 5*time.Second)
+
+	// the throttled client is skipped for its Retry-After
+	for i := 0; i < len(cfg.Network.Clients); i++ {
+		require.Equal(t, ts.URL, clients.Next())
+	}
+
+	metrics := scrapeMetrics(t)
+	require.Contains(t, metrics, fmt.Sprintf(`titan_client_request_duration_seconds_count{client="%s"} 1`, throttled.URL))
+	require.Contains(t, metrics, fmt.Sprintf(`titan_client_requests_throttled_total{client="%s"} 1`, throttled.URL))
+	require.NotContains(t, metrics, fmt.Sprintf(`titan_client_requests_throttled_total{client="%s"}`, ts.URL))
+}
+
+func TestLCDClientRateLimit(t *testing.T) {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	var requests int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	cfg := config.Config{
+		Network: config.NetworkConfig{
+			Clients:   []string{ts.URL},
+			RateLimit: 10,
+			RateBurst: 2,
+		},
+	}
+
+	clients := monitor.NewLCDClientManager(cfg, logger)
+	defer clients.Stop()
+
+	// requests beyond the burst are delayed by the rate
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		_, err := clients.Request(ts.URL+"/node_info", core.RequestGET, nil)
+		require.NoError(t, err)
+	}
+
+	require.True(t, time.Since(start) >= 150*time.Millisecond)
+	require.Equal(t, int32(4), atomic.LoadInt32(&requests))
+
+	metrics := scrapeMetrics(t)
+	require.Contains(t, metrics, fmt.Sprintf(`titan_client_request_duration_seconds_count{client="%s"} 4`, ts.URL))
+	require.Contains(t, metrics, fmt.Sprintf(`titan_client_requests_delayed_total{client="%s"} 2`, ts.URL))
+	require.NotContains(t, metrics, fmt.Sprintf(`titan_client_requests_throttled_total{client="%s"}`, ts.URL))
+}
+
+func TestLCDClientVerificationRateLimit(t *testing.T) {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"network":"test-chain"}`))
+	}))
+	defer ts.Close()
+
+	cfg := config.Config{
+		Network: config.NetworkConfig{
+			Clients:         []string{ts.URL},
+			ExpectedChainID: "test-chain",
+			RateLimit:       10,
+			RateBurst:       1,
+		},
+	}
+
+	clients := monitor.NewLCDClientManager(cfg, logger)
+	defer clients.Stop()
+
+	require.Equal(t, []string{ts.URL}, clients.Healthy())
+
+	// the verification request took the only token of the client's burst
+	start := time.Now()
+	_, err = clients.Request(ts.URL+"/node_info", core.RequestGET, nil)
+	require.NoError(t, err)
+	require.True(t, time.Since(start) >= 50*time.Millisecond)
+}
@@ -0,0 +1,544 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	"github.com/pkg/errors"
+
+	"github.com/cosmos/cosmos-sdk/wire"
+
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+var (
+	_ Monitor = (*ConsensusRoundMonitor)(nil)
+	_ Monitor = (*ProposerMonitor)(nil)
+)
+
+// Consensus monitor alert related constants.
+const (
+	ConsensusRoundMonitorMemo = "Missing Consensus Votes or Stuck Rounds"
+	ConsensusRoundMonitorName = "consensus/round"
+	ProposerMonitorMemo       = "Validators Not Proposing Blocks"
+	ProposerMonitorName       = "consensus/proposer"
+)
+
+// maxPendingProposers defines the maximum number of sampled heights awaiting
+// their commit before the oldest samples are dropped.
+const maxPendingProposers = 100
+
+// Vote types of missing consensus votes.
+const (
+	VoteTypePrevote   = "prevote"
+	VoteTypePrecommit = "precommit"
+)
+
+// Tendermint consensus round steps as reported by the consensus state.
+const (
+	roundStepNewHeight = iota + 1
+	roundStepNewRound
+	roundStepPropose
+	roundStepPrevote
+	roundStepPrevoteWait
+	roundStepPrecommit
+	roundStepPrecommitWait
+	roundStepCommit
+)
+
+// nilVote defines the string representation of a vote that has not been
+// received.
+const nilVote = "nil-Vote"
+
+var roundStepNames = map[int]string{
+	roundStepNewHeight:     "NewHeight",
+	roundStepNewRound:      "NewRound",
+	roundStepPropose:       "Propose",
+	roundStepPrevote:       "Prevote",
+	roundStepPrevoteWait:   "PrevoteWait",
+	roundStepPrecommit:     "Precommit",
+	roundStepPrecommitWait: "PrecommitWait",
+	roundStepCommit:        "Commit",
+}
+
+type (
+	// ConsensusRoundMonitor defines a monitor responsible for monitoring the
+	// consensus state of a Tendermint RPC client. An alert is triggered when a
+	// filtered validator's prevote or precommit is missing from a round that
+	// has progressed past the respective step or when the network reaches the
+	// configured maximum round at the current height.
+	ConsensusRoundMonitor struct {
+		codec   *wire.Codec
+		logger  core.Logger
+		filters *ValidatorFilters
+		cm      *core.ClientManager
+
+		maxRound int
+
+		name string
+		memo string
+	}
+
+	// MissingConsensusVote defines a structure for containing a consensus vote
+	// of a validator that is missing from a given round.
+	MissingConsensusVote struct {
+		Address string `json:"address"`
+		Round   int    `json:"round"`
+		Type    string `json:"type"`
+	}
+
+	// ConsensusRound defines a structure for containing the consensus round
+	// state of the current height along with any missing votes of filtered
+	// validators.
+	ConsensusRound struct {
+		Height       int64                  `json:"height"`
+		Round        int                    `json:"round"`
+		Step         string                 `json:"step"`
+		Stuck        bool                   `json:"stuck"`
+		MissingVotes []MissingConsensusVote `json:"missing_votes"`
+	}
+
+	// ProposerMonitor defines a monitor responsible for monitoring when filtered
+	// validators stop proposing blocks. Tendermint block headers do not contain
+	// the proposer, so the proposer of the current round is sampled from the
+	// consensus state at each execution. A sample is counted once the block at
+	// its height is committed in the sampled round. Each counted sample is
+	// proposed by a validator with a probability of its voting power share, so
+	// an alert is triggered when the number of counted samples since a
+	// validator's last proposal exceeds the configured miss factor times the
+	// inverse of its share.
+	ProposerMonitor struct {
+		codec   *wire.Codec
+		logger  core.Logger
+		filters *ValidatorFilters
+		cm      *core.ClientManager
+
+		missFactor float64
+
+		mu      sync.Mutex
+		pending map[int64]proposerSample
+		shares  map[string]float64
+		streaks map[string]*proposalStreak
+
+		name string
+		memo string
+	}
+
+	// MissingProposals defines a structure for containing the number of
+	// counted samples since a validator last proposed a block compared to the
+	// number of samples it is expected to propose one in.
+	MissingProposals struct {
+		Address            string `json:"address"`
+		LastProposalHeight int64  `json:"last_proposal_height"`
+		ObservedSamples    int64  `json:"observed_samples"`
+		ExpectedSamples    int64  `json:"expected_samples"`
+	}
+
+	// proposerSample defines the proposer of a round at a given height.
+	proposerSample struct {
+		round    int
+		proposer string
+	}
+
+	// proposalStreak defines the number of counted samples since a height at
+	// which a validator last proposed or was first observed.
+	proposalStreak struct {
+		since    int64
+		proposed bool
+		observed int64
+	}
+
+	// dumpRoundState defines the parts of the full round state returned by the
+	// Tendermint RPC dump consensus state endpoint needed to determine the
+	// proposer of the current round.
+	dumpRoundState struct {
+		Height     int64                 `json:"height"`
+		Round      int                   `json:"round"`
+		Validators *tmtypes.ValidatorSet `json:"validators"`
+	}
+
+	// roundState defines the simple round state returned by the Tendermint RPC
+	// consensus state endpoint.
+	roundState struct {
+		HeightRoundStep string       `json:"height/round/step"`
+		Votes           []roundVotes `json:"height_vote_set"`
+	}
+
+	// roundVotes defines the string representation of all the votes received
+	// for a given round where each vote is indexed by the validator's index in
+	// the validator set.
+	roundVotes struct {
+		Round      int      `json:"round"`
+		Prevotes   []string `json:"prevotes"`
+		Precommits []string `json:"precommits"`
+	}
+)
+
+// NewConsensusRoundMonitor returns a reference to a new ConsensusRoundMonitor.
+func NewConsensusRoundMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *ConsensusRoundMonitor {
+	codec := wire.NewCodec()
+	ctypes.RegisterAmino(codec)
+
+	// the monitor is a no-op without any Tendermint RPC clients
+	return &ConsensusRoundMonitor{
+		codec:    codec,
+		logger:   logger.With("module", name),
+		filters:  deps.Filters,
+		cm:       deps.RPCClients,
+		maxRound: cfg.Consensus.MaxRound,
+		name:     name,
+		memo:     memo,
+	}
+}
+
+// Name implements the Monitor interface. It returns the monitor's name.
+func (crm *ConsensusRoundMonitor) Name() string { return crm.name }
+
+// Memo implements the Monitor interface. It returns the monitor's memo.
+func (crm *ConsensusRoundMonitor) Memo() string { return crm.memo }
+
+// Exec implements the Monitor interface. It attempts to fetch the consensus
+// state and the validator set of the current height from a Tendermint RPC
+// client. Upon success, the serialized encoding of the current consensus round
+// and an ID that is the SHA256 of its height, round and number of missing votes
+// will be returned if a filtered validator's vote is missing or the round is
+// stuck and an error otherwise.
+func (crm *ConsensusRoundMonitor) Exec() (resp, id []byte, err error) {
+	if crm.cm == nil {
+		return nil, nil, errors.New("no RPC clients configured")
+	}
+
+	client := crm.cm.Next()
+	crm.logger.Info("monitoring for missing consensus votes and stuck rounds")
+
+	rs, err := crm.getRoundState(fmt.Sprintf("%s/consensus_state", client))
+	if err != nil {
+		crm.logger.Errorf("failed to get consensus state: %v", err)
+		return nil, nil, err
+	}
+
+	var height int64
+	var round, step int
+
+	if _, err := fmt.Sscanf(rs.HeightRoundStep, "%d/%d/%d", &height, &round, &step); err != nil {
+		crm.logger.Errorf("failed to parse consensus round %s: %v", rs.HeightRoundStep, err)
+		return nil, nil, errors.Wrap(err, "failed to parse consensus round")
+	}
+
+	vals, err := crm.getValidators(fmt.Sprintf("%s/validators?height=%d", client, height))
+	if err != nil {
+		crm.logger.Errorf("failed to get validators at height %d: %v", height, err)
+		return nil, nil, err
+	}
+
+	consensusRound := ConsensusRound{
+		Height:       height,
+		Round:        round,
+		Step:         roundStepNames[step],
+		Stuck:        crm.maxRound > 0 && round >= crm.maxRound,
+		MissingVotes: []MissingConsensusVote{},
+	}
+
+	for _, validatorFilter := range crm.filters.All() {
+		index := -1
+		for i, val := range vals.Validators {
+			if val.Address.String() == validatorFilter.Address {
+				index = i
+				break
+			}
+		}
+
+		// the validator is not part of the validator set at the current height
+		if index < 0 {
+			continue
+		}
+
+		for _, rv := range rs.Votes {
+			// only report votes the round has progressed past
+			if (rv.Round < round || step >= roundStepPrecommit) && missingVote(rv.Prevotes, index) {
+				consensusRound.MissingVotes = append(consensusRound.MissingVotes, MissingConsensusVote{
+					Address: validatorFilter.Address,
+					Round:   rv.Round,
+					Type:    VoteTypePrevote,
+				})
+			}
+
+			if (rv.Round < round || step >= roundStepCommit) && missingVote(rv.Precommits, index) {
+				consensusRound.MissingVotes = append(consensusRound.MissingVotes, MissingConsensusVote{
+					Address: validatorFilter.Address,
+					Round:   rv.Round,
+					Type:    VoteTypePrecommit,
+				})
+			}
+		}
+	}
+
+	if !consensusRound.Stuck && len(consensusRound.MissingVotes) == 0 {
+		return nil, nil, errors.New("no missing consensus votes or stuck rounds found")
+	}
+
+	raw, err := wire.MarshalJSONIndent(crm.codec, consensusRound)
+	if err != nil {
+		crm.logger.Errorf("failed to serialize consensus round: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to serialize consensus round")
+	}
+
+	// The step changes throughout a round, so the ID only reflects the round and
+	// the number of missing votes to alert once per round and new missing vote.
+	rawHash := sha256.Sum256([]byte(
+		fmt.Sprintf("%d/%d/%d", height, round, len(consensusRound.MissingVotes)),
+	))
+	id = rawHash[:]
+
+	return raw, id, nil
+}
+
+func (crm *ConsensusRoundMonitor) getRoundState(url string) (*roundState, error) {
+	result, err := fetchRPCResult(crm.cm, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var state struct {
+		RoundState roundState `json:"round_state"`
+	}
+
+	if err := json.Unmarshal(result, &state); err != nil {
+		return nil, err
+	}
+
+	return &state.RoundState, nil
+}
+
+func (crm *ConsensusRoundMonitor) getValidators(url string) (*ctypes.ResultValidators, error) {
+	result, err := fetchRPCResult(crm.cm, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var vals ctypes.ResultValidators
+	if err := crm.codec.UnmarshalJSON(result, &vals); err != nil {
+		return nil, err
+	}
+
+	return &vals, nil
+}
+
+// missingVote returns true if the vote of the validator at the given index has
+// not been received.
+func missingVote(votes []string, index int) bool {
+	return index < len(votes) && votes[index] == nilVote
+}
+
+// NewProposerMonitor returns a reference to a new ProposerMonitor.
+func NewProposerMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *ProposerMonitor {
+	codec := wire.NewCodec()
+	ctypes.RegisterAmino(codec)
+
+	return &ProposerMonitor{
+		codec:      codec,
+		logger:     logger.With("module", name),
+		filters:    deps.Filters,
+		cm:         deps.RPCClients,
+		missFactor: cfg.Consensus.ProposalMissFactor,
+		pending:    make(map[int64]proposerSample),
+		shares:     make(map[string]float64),
+		streaks:    make(map[string]*proposalStreak),
+		name:       name,
+		memo:       memo,
+	}
+}
+
+// Name implements the Monitor interface. It returns the monitor's name.
+func (pm *ProposerMonitor) Name() string { return pm.name }
+
+// Memo implements the Monitor interface. It returns the monitor's memo.
+func (pm *ProposerMonitor) Memo() string { return pm.memo }
+
+// Exec implements the Monitor interface. It attempts to sample the proposer of
+// the current round from a Tendermint RPC client and count all previous
+// samples whose block has been committed in the sampled round. Upon success,
+// the serialized encoding of filtered validators that have not proposed a
+// block for longer than expected and an ID that is the SHA256 of their
+// addresses and the heights since which they have not proposed will be
+// returned and an error otherwise.
+func (pm *ProposerMonitor) Exec() (resp, id []byte, err error) {
+	if pm.cm == nil {
+		return nil, nil, errors.New("no RPC clients configured")
+	}
+
+	client := pm.cm.Next()
+	pm.logger.Info("monitoring for validators not proposing blocks")
+
+	rs, err := pm.getRoundState(fmt.Sprintf("%s/dump_consensus_state", client))
+	if err != nil {
+		pm.logger.Errorf("failed to get consensus state: %v", err)
+		return nil, nil, err
+	}
+
+	if rs.Validators == nil || rs.Validators.Size() == 0 {
+		return nil, nil, errors.New("received empty validator set")
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.pending[rs.Height] = proposerSample{
+		round:    rs.Round,
+		proposer: rs.Validators.GetProposer().Address.String(),
+	}
+
+	pm.shares = make(map[string]float64)
+	for _, val := range rs.Validators.Validators {
+		pm.shares[val.Address.String()] = float64(val.VotingPower) / float64(rs.Validators.TotalVotingPower())
+	}
+
+	pm.countCommitted(client, rs.Height)
+
+	var missingProposals []MissingProposals
+	var streakIDs []string
+
+	for _, validatorFilter := range pm.filters.All() {
+		share := pm.shares[validatorFilter.Address]
+		streak, ok := pm.streaks[validatorFilter.Address]
+		if share == 0 || !ok {
+			continue
+		}
+
+		// the number of samples between proposals is geometrically distributed
+		// with a mean of the inverse of the validator's share
+		expected := 1 / share
+		if float64(streak.observed) <= pm.missFactor*expected {
+			continue
+		}
+
+		mp := MissingProposals{
+			Address:         validatorFilter.Address,
+			ObservedSamples: streak.observed,
+			ExpectedSamples: int64(expected + 0.5),
+		}
+
+		if streak.proposed {
+			mp.LastProposalHeight = streak.since
+		}
+
+		missingProposals = append(missingProposals, mp)
+		streakIDs = append(streakIDs, fmt.Sprintf("%s/%d", validatorFilter.Address, streak.since))
+	}
+
+	if len(missingProposals) == 0 {
+		return nil, nil, errors.New("no validators missing proposals found")
+	}
+
+	raw, err := wire.MarshalJSONIndent(pm.codec, missingProposals)
+	if err != nil {
+		pm.logger.Errorf("failed to serialize missing proposals: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to serialize missing proposals")
+	}
+
+	// The number of counted samples grows at each execution, so the ID only
+	// reflects the heights since which validators have not proposed to alert
+	// once per validator and dry spell.
+	rawHash := sha256.Sum256([]byte(fmt.Sprintf("%v", streakIDs)))
+	id = rawHash[:]
+
+	return raw, id, nil
+}
+
+// countCommitted counts all pending samples below the current height whose
+// block has been committed in the sampled round towards the proposal streaks
+// of filtered validators. Samples whose commit cannot be fetched are retried
+// at the next execution until they are dropped.
+func (pm *ProposerMonitor) countCommitted(client string, height int64) {
+	var heights []int64
+	for h := range pm.pending {
+		if h < height {
+			heights = append(heights, h)
+		}
+	}
+
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	for _, h := range heights {
+		sample := pm.pending[h]
+
+		commit, err := pm.getCommit(fmt.Sprintf("%s/commit?height=%d", client, h))
+		if err != nil {
+			pm.logger.Debugf("failed to get commit at height %d: %v", h, err)
+
+			if height-h > maxPendingProposers {
+				delete(pm.pending, h)
+			}
+
+			continue
+		}
+
+		delete(pm.pending, h)
+
+		// the sampled round's proposer did not propose the committed block
+		if commit.Commit == nil || commit.Commit.Round() != sample.round {
+			continue
+		}
+
+		for _, validatorFilter := range pm.filters.All() {
+			if _, ok := pm.shares[validatorFilter.Address]; !ok {
+				continue
+			}
+
+			streak, ok := pm.streaks[validatorFilter.Address]
+			if !ok {
+				streak = &proposalStreak{since: h}
+				pm.streaks[validatorFilter.Address] = streak
+			}
+
+			if sample.proposer == validatorFilter.Address {
+				*streak = proposalStreak{since: h, proposed: true}
+				continue
+			}
+
+			streak.observed++
+		}
+	}
+}
+
+func (pm *ProposerMonitor) getRoundState(url string) (*dumpRoundState, error) {
+	result, err := fetchRPCResult(pm.cm, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var state struct {
+		RoundState json.RawMessage `json:"round_state"`
+	}
+
+	if err := json.Unmarshal(result, &state); err != nil {
+		return nil, err
+	}
+
+	var rs dumpRoundState
+	if err := pm.codec.UnmarshalJSON(state.RoundState, &rs); err != nil {
+		return nil, err
+	}
+
+	return &rs, nil
+}
+
+func (pm *ProposerMonitor) getCommit(url string) (*ctypes.ResultCommit, error) {
+	result, err := fetchRPCResult(pm.cm, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var commit ctypes.ResultCommit
+	if err := pm.codec.UnmarshalJSON(result, &commit); err != nil {
+		return nil, err
+	}
+
+	return &commit, nil
+}
@@ -0,0 +1,279 @@
+package monitor_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	"github.com/alexanderbez/titan/monitor"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func newTestConsensusRoundMonitor(t *testing.T, cfg config.Config) *monitor.ConsensusRoundMonitor {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	return monitor.NewConsensusRoundMonitor(
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.ConsensusRoundMonitorName, monitor.ConsensusRoundMonitorMemo,
+	)
+}
+
+func newTestConsensusServer(t *testing.T, hrs string, votes []map[string]interface{}, vals []*tmtypes.Validator) *httptest.Server {
+	codec := newSlashingTestCodec()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result interface{}
+
+		switch r.URL.Path {
+		case "/consensus_state":
+			rawVotes, err := json.Marshal(votes)
+			require.NoError(t, err)
+
+			roundState := fmt.Sprintf(`{"height/round/step":"%s","height_vote_set":%s}`, hrs, rawVotes)
+			result = ctypes.ResultConsensusState{RoundState: json.RawMessage(roundState)}
+
+		case "/validators":
+			result = ctypes.ResultValidators{BlockHeight: 10, Validators: vals}
+		}
+
+		resp := rpctypes.NewRPCSuccessResponse(codec, "", result)
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+}
+
+func TestNoMissingConsensusVotes(t *testing.T) {
+	pubKey := ed25519.GenPrivKey().PubKey()
+	vals := []*tmtypes.Validator{tmtypes.NewValidator(pubKey, 10)}
+
+	votes := []map[string]interface{}{
+		{"round": 0, "prevotes": []string{"Vote{0:...}"}, "precommits": []string{"nil-Vote"}},
+	}
+
+	// the precommit of the current round is not yet expected
+	ts := newTestConsensusServer(t, "10/0/6", votes, vals)
+	defer ts.Close()
+
+	cfg := config.Config{
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Address: pubKey.Address().String()},
+			},
+		},
+		Network:   config.NetworkConfig{RPCClients: []string{ts.URL}},
+		Consensus: config.Consensus{MaxRound: 3},
+	}
+
+	crm := newTestConsensusRoundMonitor(t, cfg)
+
+	res, id, err := crm.Exec()
+	require.Error(t, err)
+	require.Nil(t, res)
+	require.Nil(t, id)
+
+	// the monitor is a no-op without any RPC clients
+	cfg.Network.RPCClients = nil
+	crm = newTestConsensusRoundMonitor(t, cfg)
+
+	_, _, err = crm.Exec()
+	require.Error(t, err)
+}
+
+func TestMissingConsensusVotes(t *testing.T) {
+	codec := newSlashingTestCodec()
+	pubKey1 := ed25519.GenPrivKey().PubKey()
+	pubKey2 := ed25519.GenPrivKey().PubKey()
+
+	vals := []*tmtypes.Validator{tmtypes.NewValidator(pubKey1, 10), tmtypes.NewValidator(pubKey2, 10)}
+
+	votes := []map[string]interface{}{
+		{"round": 0, "prevotes": []string{"Vote{0:...}", "nil-Vote"}, "precommits": []string{"nil-Vote", "nil-Vote"}},
+		{"round": 1, "prevotes": []string{"Vote{0:...}", "nil-Vote"}, "precommits": []string{"nil-Vote", "nil-Vote"}},
+	}
+
+	ts := newTestConsensusServer(t, "10/1/6", votes, vals)
+	defer ts.Close()
+
+	cfg := config.Config{
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Address: pubKey2.Address().String()},
+			},
+		},
+		Network: config.NetworkConfig{RPCClients: []string{ts.URL}},
+	}
+
+	crm := newTestConsensusRoundMonitor(t, cfg)
+
+	res, id, err := crm.Exec()
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	require.NotNil(t, id)
+
+	var consensusRound monitor.ConsensusRound
+	require.NoError(t, codec.UnmarshalJSON(res, &consensusRound))
+
+	require.Equal(t, int64(10), consensusRound.Height)
+	require.Equal(t, 1, consensusRound.Round)
+	require.Equal(t, "Precommit", consensusRound.Step)
+	require.False(t, consensusRound.Stuck)
+
+	expected := []monitor.MissingConsensusVote{
+		{Address: pubKey2.Address().String(), Round: 0, Type: monitor.VoteTypePrevote},
+		{Address: pubKey2.Address().String(), Round: 0, Type: monitor.VoteTypePrecommit},
+		{Address: pubKey2.Address().String(), Round: 1, Type: monitor.VoteTypePrevote},
+	}
+	require.Equal(t, expected, consensusRound.MissingVotes)
+}
+
+func TestStuckConsensusRound(t *testing.T) {
+	codec := newSlashingTestCodec()
+	pubKey := ed25519.GenPrivKey().PubKey()
+	vals := []*tmtypes.Validator{tmtypes.NewValidator(pubKey, 10)}
+
+	var votes []map[string]interface{}
+	for round := 0; round <= 3; round++ {
+		votes = append(votes, map[string]interface{}{
+			"round": round, "prevotes": []string{"Vote{0:...}"}, "precommits": []string{"Vote{0:...}"},
+		})
+	}
+
+	ts := newTestConsensusServer(t, "10/3/3", votes, vals)
+	defer ts.Close()
+
+	cfg := config.Config{
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Address: pubKey.Address().String()},
+			},
+		},
+		Network:   config.NetworkConfig{RPCClients: []string{ts.URL}},
+		Consensus: config.Consensus{MaxRound: 3},
+	}
+
+	crm := newTestConsensusRoundMonitor(t, cfg)
+
+	res, id, err := crm.Exec()
+	require.NoError(t, err)
+	require.NotNil(t, id)
+
+	var consensusRound monitor.ConsensusRound
+	require.NoError(t, codec.UnmarshalJSON(res, &consensusRound))
+
+	require.True(t, consensusRound.Stuck)
+	require.Equal(t, "Propose", consensusRound.Step)
+	require.Empty(t, consensusRound.MissingVotes)
+}
+
+func newTestProposerMonitor(t *testing.T, cfg config.Config) *monitor.ProposerMonitor {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	return monitor.NewProposerMonitor(
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.ProposerMonitorName, monitor.ProposerMonitorMemo,
+	)
+}
+
+func TestMissingProposals(t *testing.T) {
+	codec := newSlashingTestCodec()
+
+	val1 := tmtypes.NewValidator(ed25519.GenPrivKey().PubKey(), 10)
+	val2 := tmtypes.NewValidator(ed25519.GenPrivKey().PubKey(), 10)
+	valSet := tmtypes.NewValidatorSet([]*tmtypes.Validator{val1, val2})
+
+	var (
+		height    int64
+		proposers = make(map[int64]*tmtypes.Validator)
+	)
+
+	// the block at height 3 is committed in a later round than sampled
+	commitRounds := map[int64]int{3: 1}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result interface{}
+
+		switch r.URL.Path {
+		case "/dump_consensus_state":
+			valSet.Proposer = proposers[height]
+
+			roundState := struct {
+				Height     int64                 `json:"height"`
+				Round      int                   `json:"round"`
+				Validators *tmtypes.ValidatorSet `json:"validators"`
+			}{height, 0, valSet}
+
+			rawRoundState, err := codec.MarshalJSON(roundState)
+			require.NoError(t, err)
+
+			result = ctypes.ResultDumpConsensusState{RoundState: rawRoundState}
+
+		case "/commit":
+			var h int64
+			_, err := fmt.Sscanf(r.URL.Query().Get("height"), "%d", &h)
+			require.NoError(t, err)
+
+			commit := &tmtypes.Commit{Precommits: []*tmtypes.Vote{{Height: h, Round: commitRounds[h]}}}
+			result = ctypes.NewResultCommit(&tmtypes.Header{Height: h}, commit, true)
+		}
+
+		resp := rpctypes.NewRPCSuccessResponse(codec, "", result)
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+	defer ts.Close()
+
+	cfg := config.Config{
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Address: val2.Address.String()},
+			},
+		},
+		Network:   config.NetworkConfig{RPCClients: []string{ts.URL}},
+		Consensus: config.Consensus{ProposalMissFactor: 2},
+	}
+
+	pm := newTestProposerMonitor(t, cfg)
+
+	// the validator is expected to propose every 2 samples and only heights 1, 2,
+	// 4 and 5 are counted by height 6
+	for height = 1; height <= 6; height++ {
+		proposers[height] = val1
+
+		_, _, err := pm.Exec()
+		require.Error(t, err)
+	}
+
+	proposers[height] = val1
+
+	res, id, err := pm.Exec()
+	require.NoError(t, err)
+	require.NotNil(t, id)
+
+	var missingProposals []monitor.MissingProposals
+	require.NoError(t, codec.UnmarshalJSON(res, &missingProposals))
+
+	expected := []monitor.MissingProposals{
+		{Address: val2.Address.String(), ObservedSamples: 5, ExpectedSamples: 2},
+	}
+	require.Equal(t, expected, missingProposals)
+
+	// the ID remains the same until the validator proposes again
+	height++
+	proposers[height] = val2
+
+	_, nextID, err := pm.Exec()
+	require.NoError(t, err)
+	require.Equal(t, id, nextID)
+
+	height++
+	proposers[height] = val1
+
+	_, _, err = pm.Exec()
+	require.Error(t, err)
+}
@@ -0,0 +1,343 @@
+package monitor
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	"github.com/cosmos/cosmos-sdk/wire"
+
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// Event source related constants.
+const (
+	eventBufferSize        = 1000
+	minReconnectBackoff    = time.Second
+	maxReconnectBackoff    = time.Minute
+	eventsPingPeriod       = 30 * time.Second
+	eventsReadWait         = 2 * eventsPingPeriod
+	eventsWriteWait        = 10 * time.Second
+	eventsHandshakeTimeout = 45 * time.Second
+)
+
+type (
+	// EventSource defines a subscription to new block and transaction events
+	// over a Tendermint RPC websocket. Received events are buffered so that each
+	// monitor consuming them observes every event since its last execution. If
+	// the websocket goes down, the next RPC client is tried with an exponential
+	// backoff and monitors fall back to polling the LCD clients in the meantime.
+	// The height of the latest received block is recorded as a metric.
+	EventSource struct {
+		codec   *wire.Codec
+		logger  core.Logger
+		cm      *core.ClientManager
+		chainID string
+
+		mu        sync.RWMutex
+		conn      *websocket.Conn
+		connected bool
+		blocks    []*tmtypes.Block
+		txs       []bufferedTx
+		txSeq     uint64
+
+		quit chan struct{}
+		done chan struct{}
+	}
+
+	// bufferedTx defines a received transaction along with its sequence in the
+	// order transactions were received.
+	bufferedTx struct {
+		seq uint64
+		tx  tmtypes.TxResult
+	}
+)
+
+// NewEventSource returns a reference to a new EventSource subscribing to the
+// configured RPC clients, which are verified similar to NewRPCClientManager.
+// The source must be started before events are received. Monitors querying
+// the RPC clients share its client manager.
+func NewEventSource(cfg config.Config, logger core.Logger) *EventSource {
+	codec := wire.NewCodec()
+	ctypes.RegisterAmino(codec)
+
+	return &EventSource{
+		codec:   codec,
+		logger:  logger.With("module", "events"),
+		cm:      NewRPCClientManager(cfg, logger),
+		chainID: cfg.ChainID,
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start starts the event subscription in a go-routine. Subscription errors
+// are logged and result in a reconnect to the next RPC client.
+func (es *EventSource) Start() {
+	go es.run()
+}
+
+// Stop stops the event subscription and the verification of the RPC clients
+// and waits for both to exit.
+func (es *EventSource) Stop() {
+	close(es.quit)
+
+	es.mu.RLock()
+	if es.conn != nil {
+		es.conn.Close()
+	}
+	es.mu.RUnlock()
+
+	<-es.done
+	es.cm.Stop()
+}
+
+// Connected returns true if the event source is currently subscribed to
+// events.
+func (es *EventSource) Connected() bool {
+	es.mu.RLock()
+	defer es.mu.RUnlock()
+
+	return es.connected
+}
+
+// BlocksAfter returns all buffered blocks with a height greater than the given
+// height in ascending order.
+func (es *EventSource) BlocksAfter(height int64) []*tmtypes.Block {
+	es.mu.RLock()
+	defer es.mu.RUnlock()
+
+	var blocks []*tmtypes.Block
+	for _, block := range es.blocks {
+		if block.Height > height {
+			blocks = append(blocks, block)
+		}
+	}
+
+	return blocks
+}
+
+// TxsAfter returns all buffered transactions received after the given
+// sequence along with the sequence of the latest received transaction.
+func (es *EventSource) TxsAfter(seq uint64) ([]tmtypes.TxResult, uint64) {
+	es.mu.RLock()
+	defer es.mu.RUnlock()
+
+	var txs []tmtypes.TxResult
+	for _, btx := range es.txs {
+		if btx.seq > seq {
+			txs = append(txs, btx.tx)
+		}
+	}
+
+	return txs, es.txSeq
+}
+
+func (es *EventSource) run() {
+	defer close(es.done)
+
+	backoff := minReconnectBackoff
+
+	for {
+		failed := true
+
+		// clients are excluded until verified if verification is configured
+		if client := es.cm.Next(); client == "" {
+			es.logger.Errorf("every RPC client is excluded; retrying in %s", backoff)
+		} else {
+			subscribed, err := es.subscribe(client)
+			es.setConn(nil, false)
+
+			select {
+			case <-es.quit:
+				return
+			default:
+			}
+
+			if subscribed {
+				backoff = minReconnectBackoff
+				failed = false
+			}
+
+			es.logger.Errorf("event subscription to %s failed; reconnecting in %s: %v", client, backoff, err)
+		}
+
+		select {
+		case <-es.quit:
+			return
+
+		case <-time.After(backoff):
+		}
+
+		// the backoff only grows while the subscription keeps failing
+		if failed {
+			backoff *= 2
+			if backoff > maxReconnectBackoff {
+				backoff = maxReconnectBackoff
+			}
+		}
+	}
+}
+
+// subscribe dials the given RPC client, subscribes to new block and
+// transaction events and buffers events until the connection fails. It returns
+// true if the subscription succeeded before failing.
+func (es *EventSource) subscribe(client string) (bool, error) {
+	wsURL, err := websocketURL(client)
+	if err != nil {
+		return false, err
+	}
+
+	// reuse the proxy, TLS and authentication settings of the HTTP client
+	httpClient := core.DefaultHTTPClient()
+	dialer := &websocket.Dialer{
+		Proxy:            httpClient.Transport().Proxy,
+		TLSClientConfig:  httpClient.Transport().TLSClientConfig,
+		HandshakeTimeout: eventsHandshakeTimeout,
+	}
+
+	conn, _, err := dialer.Dial(wsURL, httpClient.Header())
+	if err != nil {
+		return false, errors.Wrap(err, "failed to dial websocket")
+	}
+	defer conn.Close()
+
+	es.setConn(conn, false)
+
+	select {
+	case <-es.quit:
+		return false, errors.New("event source stopped")
+	default:
+	}
+
+	queries := []string{tmtypes.EventQueryNewBlock.String(), tmtypes.EventQueryTx.String()}
+	for i, query := range queries {
+		req, err := rpctypes.MapToRequest(
+			es.codec, fmt.Sprintf("titan-%d", i), "subscribe", map[string]interface{}{"query": query},
+		)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to create subscription request")
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(eventsWriteWait))
+		if err := conn.WriteJSON(req); err != nil {
+			return false, errors.Wrap(err, "failed to subscribe")
+		}
+	}
+
+	es.setConn(conn, true)
+	es.logger.Infof("subscribed to events from %s", client)
+
+	conn.SetReadDeadline(time.Now().Add(eventsReadWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(eventsReadWait))
+	})
+
+	stopPing := make(chan struct{})
+	defer close(stopPing)
+
+	go func() {
+		ticker := time.NewTicker(eventsPingPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopPing:
+				return
+
+			case <-ticker.C:
+				conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(eventsWriteWait))
+			}
+		}
+	}()
+
+	for {
+		var resp rpctypes.RPCResponse
+		if err := conn.ReadJSON(&resp); err != nil {
+			return true, errors.Wrap(err, "failed to read event")
+		}
+
+		if resp.Error != nil {
+			return true, errors.Wrap(resp.Error, "received RPC error")
+		}
+
+		conn.SetReadDeadline(time.Now().Add(eventsReadWait))
+		es.handleResult(resp.Result)
+	}
+}
+
+// handleResult decodes and buffers a received event. Subscription
+// acknowledgements and unknown events are ignored.
+func (es *EventSource) handleResult(result []byte) {
+	var event ctypes.ResultEvent
+	if err := es.codec.UnmarshalJSON(result, &event); err != nil {
+		es.logger.Debugf("failed to decode event: %v", err)
+		return
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	switch data := event.Data.(type) {
+	case tmtypes.EventDataNewBlock:
+		if data.Block == nil {
+			return
+		}
+
+		// a block may be received again after reconnecting to a different client
+		if n := len(es.blocks); n > 0 && data.Block.Height <= es.blocks[n-1].Height {
+			return
+		}
+
+		es.blocks = append(es.blocks, data.Block)
+		core.DefaultMetrics().ObserveLatestHeight(es.chainID, data.Block.Height)
+
+		if len(es.blocks) > eventBufferSize {
+			es.blocks = es.blocks[len(es.blocks)-eventBufferSize:]
+		}
+
+	case tmtypes.EventDataTx:
+		es.txSeq++
+
+		es.txs = append(es.txs, bufferedTx{seq: es.txSeq, tx: data.TxResult})
+		if len(es.txs) > eventBufferSize {
+			es.txs = es.txs[len(es.txs)-eventBufferSize:]
+		}
+	}
+}
+
+func (es *EventSource) setConn(conn *websocket.Conn, connected bool) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	es.conn = conn
+	es.connected = connected
+}
+
+// websocketURL returns the websocket URL of a given Tendermint RPC client.
+func websocketURL(client string) (string, error) {
+	u, err := url.Parse(client)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid RPC client")
+	}
+
+	switch u.Scheme {
+	case "https", "wss":
+		u.Scheme = "wss"
+
+	default:
+		u.Scheme = "ws"
+	}
+
+	u.Path = path.Join(u.Path, "websocket")
+	return u.String(), nil
+}
@@ -0,0 +1,295 @@
+package monitor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	"github.com/alexanderbez/titan/monitor"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/stake"
+	"github.com/gorilla/websocket"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// newTestEventServer returns a test Tendermint RPC server that acknowledges
+// subscriptions and sends the next batch of events on each connection before
+// closing it.
+func newTestEventServer(t *testing.T, batches [][]tmtypes.TMEventData) *httptest.Server {
+	codec := newSlashingTestCodec()
+	upgrader := websocket.Upgrader{}
+
+	var (
+		mu   sync.Mutex
+		conn int
+	)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer ws.Close()
+
+		mu.Lock()
+		batch := conn
+		conn++
+		mu.Unlock()
+
+		for i := 0; i < 2; i++ {
+			var req rpctypes.RPCRequest
+			require.NoError(t, ws.ReadJSON(&req))
+			require.NoError(t, ws.WriteJSON(rpctypes.NewRPCSuccessResponse(codec, req.ID, ctypes.ResultSubscribe{})))
+		}
+
+		if batch >= len(batches) {
+			// keep the last connection open until the client closes it
+			ws.ReadMessage()
+			return
+		}
+
+		for _, data := range batches[batch] {
+			event := ctypes.ResultEvent{Data: data}
+			require.NoError(t, ws.WriteJSON(rpctypes.NewRPCSuccessResponse(codec, "titan-0#event", event)))
+		}
+	}))
+}
+
+func newTestEventBlock(height int64, signers ...ed25519.PubKeyEd25519) *tmtypes.Block {
+	var precommits []*tmtypes.Vote
+	for _, signer := range signers {
+		precommits = append(precommits, &tmtypes.Vote{ValidatorAddress: signer.Address()})
+	}
+
+	return tmtypes.MakeBlock(height, nil, &tmtypes.Commit{Precommits: precommits}, nil)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	require.FailNow(t, "condition not met in time")
+}
+
+func TestEventSourceReconnect(t *testing.T) {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	pubKey1 := ed25519.GenPrivKey().PubKey().(ed25519.PubKeyEd25519)
+	pubKey2 := ed25519.GenPrivKey().PubKey().(ed25519.PubKeyEd25519)
+
+	batches := [][]tmtypes.TMEventData{
+		{
+			tmtypes.EventDataNewBlock{Block: newTestEventBlock(1, pubKey1, pubKey2)},
+			tmtypes.EventDataNewBlock{Block: newTestEventBlock(2, pubKey1)},
+		},
+		{
+			// a block received again after reconnecting is ignored
+			tmtypes.EventDataNewBlock{Block: newTestEventBlock(2, pubKey1)},
+			tmtypes.EventDataNewBlock{Block: newTestEventBlock(3, pubKey1)},
+			tmtypes.EventDataTx{TxResult: tmtypes.TxResult{Height: 3, Tx: tmtypes.Tx("tx")}},
+		},
+	}
+
+	ts := newTestEventServer(t, batches)
+	defer ts.Close()
+
+	cfg := config.Config{
+		Monitors: []string{config.MonitorMissingSignatures},
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Address: pubKey2.Address().String()},
+			},
+		},
+		Network: config.NetworkConfig{
+			Clients:    []string{"http://localhost:0"},
+			RPCClients: []string{strings.Replace(ts.URL, "http://", "tcp://", 1)},
+		},
+	}
+
+	events := monitor.NewEventSource(cfg, logger)
+	events.Start()
+	defer events.Stop()
+
+	waitFor(t, func() bool { return len(events.BlocksAfter(0)) == 3 })
+	waitFor(t, events.Connected)
+
+	txs, seq := events.TxsAfter(0)
+	require.Len(t, txs, 1)
+	require.Equal(t, uint64(1), seq)
+
+	txs, _ = events.TxsAfter(seq)
+	require.Empty(t, txs)
+
+	// every received block is fed into the monitor
+	monitors := monitor.CreateMonitors(cfg, logger, nil, events)
+	require.Len(t, monitors, 1)
+
+	resp, _, err := monitors[0].Exec()
+	require.NoError(t, err)
+
+	var missingSigners monitor.MissingSigners
+	err = newSlashingTestCodec().UnmarshalJSON(resp, &missingSigners)
+	require.NoError(t, err)
+
+	require.Equal(t, int64(2), missingSigners.Height)
+	require.Len(t, missingSigners.PreviousBlocks, 1)
+	require.Equal(t, int64(1), missingSigners.PreviousBlocks[0].Height)
+
+	// no blocks are received since the last execution
+	_, _, err = monitors[0].Exec()
+	require.Error(t, err)
+}
+
+func TestEventSourceWebsocketURL(t *testing.T) {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	paths := make(chan string, 1)
+
+	// the server records the requested path and rejects the subscription
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case paths <- r.URL.Path:
+		default:
+		}
+
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	testCases := []struct {
+		client string
+		path   string
+	}{
+		{strings.Replace(ts.URL, "http://", "tcp://", 1), "/websocket"},
+		{ts.URL, "/websocket"},
+		{ts.URL + "/", "/websocket"},
+		{ts.URL + "/rpc", "/rpc/websocket"},
+		{ts.URL + "/rpc/", "/rpc/websocket"},
+	}
+
+	for _, tc := range testCases {
+		cfg := config.Config{
+			Network: config.NetworkConfig{
+				Clients:    []string{"http://localhost:0"},
+				RPCClients: []string{tc.client},
+			},
+		}
+
+		events := monitor.NewEventSource(cfg, logger)
+		events.Start()
+
+		select {
+		case path := <-paths:
+			require.Equal(t, tc.path, path, tc.client)
+
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "no subscription received", tc.client)
+		}
+
+		events.Stop()
+	}
+}
+
+func TestUndelegationsEventFallback(t *testing.T) {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	opAddr1, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	opAddr2, err := sdk.AccAddressFromBech32("cosmosaccaddr1y2z20pwqu5qpclque3pqkguruvheum2djtzjw3")
+	require.NoError(t, err)
+
+	val := stake.NewValidator(opAddr1, ed25519.GenPrivKey().PubKey(), stake.Description{})
+	val.Tokens = sdk.NewRat(100000)
+	val.DelegatorShares = sdk.NewRat(50000)
+
+	// an undelegation before the event source connected
+	txs := []testTxInfo{
+		testTxInfo{
+			Hash:   cmn.HexBytes{0x01},
+			Height: 10,
+			Tx:     auth.StdTx{Msgs: []sdk.Msg{stake.NewMsgBeginUnbonding(opAddr2, opAddr1, sdk.NewRat(10000))}},
+		},
+	}
+
+	height := int64(11)
+	lcd := newTestUndelegationServer(t, val, &txs, &height)
+	defer lcd.Close()
+
+	ts := newTestEventServer(t, [][]tmtypes.TMEventData{
+		{tmtypes.EventDataNewBlock{Block: newTestEventBlock(12)}},
+	})
+	defer ts.Close()
+
+	cfg := config.Config{
+		Monitors: []string{config.MonitorUndelegations},
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Operator: opAddr1.String()},
+			},
+		},
+		Network: config.NetworkConfig{
+			Clients:    []string{lcd.URL},
+			RPCClients: []string{strings.Replace(ts.URL, "http://", "tcp://", 1)},
+		},
+		Staking: config.Staking{MaxUndelegationShare: 10},
+	}
+
+	events := monitor.NewEventSource(cfg, logger)
+	events.Start()
+
+	waitFor(t, func() bool { return len(events.BlocksAfter(0)) == 1 })
+	waitFor(t, events.Connected)
+
+	monitors := monitor.CreateMonitors(cfg, logger, nil, events)
+	require.Len(t, monitors, 1)
+
+	// the first execution starts at the latest block
+	_, _, err = monitors[0].Exec()
+	require.Error(t, err)
+
+	// no transactions are received from the event source
+	_, _, err = monitors[0].Exec()
+	require.Error(t, err)
+
+	// the search after disconnecting only considers transactions since
+	events.Stop()
+	require.False(t, events.Connected())
+
+	_, _, err = monitors[0].Exec()
+	require.Error(t, err)
+
+	txs = append(txs, testTxInfo{
+		Hash:   cmn.HexBytes{0x02},
+		Height: 13,
+		Tx:     auth.StdTx{Msgs: []sdk.Msg{stake.NewMsgBeginUnbonding(opAddr2, opAddr1, sdk.NewRat(10000))}},
+	})
+
+	resp, _, err := monitors[0].Exec()
+	require.NoError(t, err)
+
+	var undelegations []monitor.Undelegation
+	err = newStakingTestCodec().UnmarshalJSON(resp, &undelegations)
+	require.NoError(t, err)
+
+	require.Len(t, undelegations, 1)
+	require.Equal(t, int64(13), undelegations[0].Height)
+}
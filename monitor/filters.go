@@ -0,0 +1,164 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	staketypes "github.com/cosmos/cosmos-sdk/x/stake/types"
+)
+
+// Validator filter consensus address resolution sources.
+const (
+	ResolvedFromConfig   = "config"
+	ResolvedFromPubKey   = "pubkey"
+	ResolvedFromOperator = "operator"
+)
+
+type (
+	// ValidatorFilters defines a concurrency-safe set of validator filters. A
+	// single set is shared between all created monitors so that any update to
+	// a validator's consensus address is observed by every monitor.
+	ValidatorFilters struct {
+		mu      sync.RWMutex
+		filters []config.ValidatorFilter
+	}
+
+	// FilterResolution defines the result of resolving the consensus address of
+	// a single validator filter. A warning is set when the resolved address does
+	// not match the validator's current consensus key.
+	FilterResolution struct {
+		Filter  config.ValidatorFilter
+		Source  string
+		Warning string
+	}
+)
+
+// NewValidatorFilters returns a reference to a new ValidatorFilters containing
+// a copy of the given filters.
+func NewValidatorFilters(filters []config.ValidatorFilter) *ValidatorFilters {
+	vf := &ValidatorFilters{filters: make([]config.ValidatorFilter, len(filters))}
+	copy(vf.filters, filters)
+
+	return vf
+}
+
+// All returns a copy of all the validator filters.
+func (vf *ValidatorFilters) All() []config.ValidatorFilter {
+	vf.mu.RLock()
+	defer vf.mu.RUnlock()
+
+	filters := make([]config.ValidatorFilter, len(vf.filters))
+	copy(filters, vf.filters)
+
+	return filters
+}
+
+// SetAddress updates the consensus address of the filter matching a given
+// validator operator. It returns false if no filter matches the operator.
+func (vf *ValidatorFilters) SetAddress(operator, address string) bool {
+	vf.mu.Lock()
+	defer vf.mu.Unlock()
+
+	for i, validatorFilter := range vf.filters {
+		if validatorFilter.Operator == operator {
+			vf.filters[i].Address = address
+			return true
+		}
+	}
+
+	return false
+}
+
+// ResolveValidatorFilters resolves the consensus address of each configured
+// validator filter. An address is derived from the filter's consensus public
+// key if given, otherwise it is looked up from the validator's operator via the
+// staking endpoint. Configured addresses are checked against the validator's
+// current consensus key when possible. Validators are fetched through the given
+// client manager of the LCD clients if not nil. An error is returned if any
+// filter cannot be resolved or its addresses conflict.
+func ResolveValidatorFilters(cfg config.Config, logger core.Logger, clients *core.ClientManager) ([]FilterResolution, error) {
+	logger = logger.With("module", "filters")
+
+	cm := clients
+	if cm == nil {
+		cm = core.NewClientManager(cfg.Network.Clients)
+	}
+
+	valsMap := make(map[string]staketypes.BechValidator)
+
+	api := newChainAPI(cfg)
+
+	vals, valsErr := fetchValidators(cm, api, cm.Next())
+	if valsErr != nil {
+		logger.Errorf("failed to get all validators: %v", valsErr)
+	}
+
+	for _, val := range vals {
+		valsMap[api.Operator(val.Owner)] = val
+	}
+
+	resolutions := make([]FilterResolution, len(cfg.Filters.Validators))
+
+	for i, validatorFilter := range cfg.Filters.Validators {
+		res := FilterResolution{Filter: validatorFilter, Source: ResolvedFromConfig}
+
+		if validatorFilter.PubKey != "" {
+			pubKey, err := api.ConsPubKey(validatorFilter.PubKey)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid consensus public key for validator %s", validatorFilter.Operator)
+			}
+
+			address := pubKey.Address().String()
+			if validatorFilter.Address != "" && !strings.EqualFold(validatorFilter.Address, address) {
+				return nil, fmt.Errorf(
+					"configured address %s does not match consensus public key address %s for validator %s",
+					validatorFilter.Address, address, validatorFilter.Operator,
+				)
+			}
+
+			res.Filter.Address = address
+			res.Source = ResolvedFromPubKey
+		}
+
+		val, ok := valsMap[validatorFilter.Operator]
+
+		if res.Filter.Address == "" {
+			if valsErr != nil {
+				return nil, errors.Wrapf(valsErr, "failed to resolve address for validator %s", validatorFilter.Operator)
+			}
+
+			if !ok {
+				return nil, fmt.Errorf("failed to resolve address for validator %s: validator not found", validatorFilter.Operator)
+			}
+
+			pubKey, err := api.ConsPubKey(val.PubKey)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to decode consensus public key for validator %s", validatorFilter.Operator)
+			}
+
+			res.Filter.Address = pubKey.Address().String()
+			res.Source = ResolvedFromOperator
+		} else if ok {
+			pubKey, err := api.ConsPubKey(val.PubKey)
+			if err == nil && !strings.EqualFold(res.Filter.Address, pubKey.Address().String()) {
+				res.Warning = fmt.Sprintf(
+					"address does not match the validator's current consensus address %s",
+					pubKey.Address().String(),
+				)
+			}
+		} else if valsErr == nil {
+			res.Warning = "validator not found"
+		}
+
+		// addresses are compared against their uppercase HEX encoding
+		res.Filter.Address = strings.ToUpper(res.Filter.Address)
+		resolutions[i] = res
+	}
+
+	return resolutions, nil
+}
@@ -0,0 +1,97 @@
+package monitor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	"github.com/alexanderbez/titan/monitor"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/stake"
+	staketypes "github.com/cosmos/cosmos-sdk/x/stake/types"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+)
+
+func TestResolveValidatorFilters(t *testing.T) {
+	codec := newStakingTestCodec()
+
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	opAddr1, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	opAddr2, err := sdk.AccAddressFromBech32("cosmosaccaddr1y2z20pwqu5qpclque3pqkguruvheum2djtzjw3")
+	require.NoError(t, err)
+
+	pubKey1 := ed25519.GenPrivKey().PubKey()
+	pubKey2 := ed25519.GenPrivKey().PubKey()
+
+	val, err := stake.NewValidator(opAddr1, pubKey1, stake.Description{}).Bech32Validator()
+	require.NoError(t, err)
+
+	raw, err := codec.MarshalJSON([]staketypes.BechValidator{val})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+	defer ts.Close()
+
+	valPubKey2, err := sdk.Bech32ifyValPub(pubKey2)
+	require.NoError(t, err)
+
+	clients := []string{ts.URL}
+	cfg := config.Config{
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Operator: opAddr1.String()},
+				config.ValidatorFilter{Operator: opAddr2.String(), PubKey: valPubKey2},
+			},
+		},
+		Network: config.NetworkConfig{Clients: clients},
+	}
+
+	resolutions, err := monitor.ResolveValidatorFilters(cfg, logger, nil)
+	require.NoError(t, err)
+	require.Len(t, resolutions, 2)
+
+	require.Equal(t, pubKey1.Address().String(), resolutions[0].Filter.Address)
+	require.Equal(t, monitor.ResolvedFromOperator, resolutions[0].Source)
+	require.Empty(t, resolutions[0].Warning)
+
+	require.Equal(t, pubKey2.Address().String(), resolutions[1].Filter.Address)
+	require.Equal(t, monitor.ResolvedFromPubKey, resolutions[1].Source)
+	require.Equal(t, "validator not found", resolutions[1].Warning)
+
+	// a configured address not matching the current consensus key is reported
+	cfg.Filters.Validators = []config.ValidatorFilter{
+		config.ValidatorFilter{Operator: opAddr1.String(), Address: pubKey2.Address().String()},
+	}
+
+	resolutions, err = monitor.ResolveValidatorFilters(cfg, logger, nil)
+	require.NoError(t, err)
+	require.Equal(t, monitor.ResolvedFromConfig, resolutions[0].Source)
+	require.NotEmpty(t, resolutions[0].Warning)
+
+	// a configured address conflicting with the configured public key fails
+	cfg.Filters.Validators = []config.ValidatorFilter{
+		config.ValidatorFilter{Operator: opAddr2.String(), Address: pubKey1.Address().String(), PubKey: valPubKey2},
+	}
+
+	_, err = monitor.ResolveValidatorFilters(cfg, logger, nil)
+	require.Error(t, err)
+
+	// an operator that cannot be found fails
+	cfg.Filters.Validators = []config.ValidatorFilter{
+		config.ValidatorFilter{Operator: opAddr2.String()},
+	}
+
+	_, err = monitor.ResolveValidatorFilters(cfg, logger, nil)
+	require.Error(t, err)
+}
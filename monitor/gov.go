@@ -1,20 +1,31 @@
 package monitor
 
 import (
+	"bytes"
 	"crypto/sha256"
-	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/alexanderbez/titan/config"
 	"github.com/alexanderbez/titan/core"
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/wire"
 	"github.com/cosmos/cosmos-sdk/x/gov"
+
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 )
 
 var (
 	_ Monitor = (*GovProposalMonitor)(nil)
 	_ Monitor = (*GovVotingMonitor)(nil)
+	_ Monitor = (*GovVoteReminderMonitor)(nil)
+	_ Monitor = (*GovOutcomeMonitor)(nil)
+	_ Monitor = (*GovUpgradeMonitor)(nil)
 )
 
 // Governance monitor alert related constants.
@@ -23,31 +34,56 @@ const (
 	GovProposalMonitorName = "govProposal/new"
 	GovVotingMonitorMemo   = "New Active Governance Proposals"
 	GovVotingMonitorName   = "govProposal/voting"
+
+	GovVoteReminderMonitorMemo = "Governance Proposals Missing Validator Votes"
+	GovVoteReminderMonitorName = "govProposal/missingVote"
+
+	GovOutcomeMonitorMemo = "Governance Proposal Outcomes"
+	GovOutcomeMonitorName = "govProposal/outcome"
+
+	GovUpgradeMonitorMemo = "Upcoming Software Upgrades"
+	GovUpgradeMonitorName = "govProposal/upgrade"
+)
+
+// Software upgrade proposals do not contain a structured upgrade plan, so the
+// planned height and name are extracted from the proposal's title and
+// description (e.g. "height: 1000000" or "upgrade_height: 1000000" and
+// "name: v0.25.0"). The height must be given by its key so that heights
+// mentioned in free text (e.g. "not before height 100") are not matched.
+var (
+	upgradeHeightRegex = regexp.MustCompile(`(?i)\b(?:upgrade_)?height\s*[:=]\s*(\d+)`)
+	upgradeNameRegex   = regexp.MustCompile(`(?i)name\s*[:=]\s*([\w.\-]+)`)
 )
 
+// Governance proposal outcomes reported by the GovOutcomeMonitor.
 const (
-	govProposalStatusNew    = "DepositPeriod"
-	govProposalStatusVoting = "VotingPeriod"
+	ProposalOutcomePassed        = "Passed"
+	ProposalOutcomeRejected      = "Rejected"
+	ProposalOutcomeDepositFailed = "DepositFailed"
+	ProposalOutcomeDepositEnding = "DepositEnding"
 )
 
 type baseGovMonitor struct {
 	codec  *wire.Codec
+	api    chainAPI
 	logger core.Logger
 	cm     *core.ClientManager
 	name   string
 	memo   string
 }
 
-func newBaseGovMonitor(logger core.Logger, cfg config.Config, name, memo string) *baseGovMonitor {
+func newBaseGovMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *baseGovMonitor {
 	logger = logger.With("module", name)
 
 	codec := wire.NewCodec()
 	gov.RegisterWire(codec)
+	ctypes.RegisterAmino(codec)
 
 	return &baseGovMonitor{
 		codec:  codec,
+		api:    deps.api,
 		logger: logger,
-		cm:     core.NewClientManager(cfg.Network.Clients),
+		cm:     deps.Clients,
 		name:   name,
 		memo:   memo,
 	}
@@ -59,18 +95,60 @@ func (gm *baseGovMonitor) Name() string { return gm.name }
 // Memo implements the Monitor interface. It returns the monitor's memo.
 func (gm *baseGovMonitor) Memo() string { return gm.memo }
 
-func (gm baseGovMonitor) getProposals(url string) (resp []byte, proposals []gov.Proposal, err error) {
-	resp, err = core.Request(url, core.RequestGET, nil)
+// getProposals returns all proposals with a given status along with the raw
+// response bodies of all pages joined by newlines.
+func (gm baseGovMonitor) getProposals(client string, status gov.ProposalStatus) (resp []byte, proposals []gov.Proposal, err error) {
+	pages, err := gm.getPages(client, gm.api.ProposalsPath(status))
 	if err != nil {
 		return nil, nil, err
 	}
 
-	err = gm.codec.UnmarshalJSON(resp, &proposals)
+	for _, page := range pages {
+		pageProposals, err := gm.api.DecodeProposals(page)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		proposals = append(proposals, pageProposals...)
+	}
+
+	return bytes.Join(pages, []byte("\n")), proposals, nil
+}
+
+// getProposal returns a single proposal or nil if it does not exist.
+func (gm baseGovMonitor) getProposal(client string, proposalID int64) (gov.Proposal, error) {
+	resp, err := gm.cm.Request(client+gm.api.ProposalPath(proposalID), core.RequestGET, nil)
+	if core.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return gm.api.DecodeProposal(resp)
+}
+
+func (gm baseGovMonitor) getVotes(client string, proposalID int64) (votes []gov.Vote, err error) {
+	pages, err := gm.getPages(client, gm.api.VotesPath(proposalID))
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	return resp, proposals, nil
+	for _, page := range pages {
+		pageVotes, err := gm.api.DecodeVotes(page)
+		if err != nil {
+			return nil, err
+		}
+
+		votes = append(votes, pageVotes...)
+	}
+
+	return votes, nil
+}
+
+func (gm baseGovMonitor) getPages(client, path string) ([][]byte, error) {
+	return fetchPages(gm.api, client, path, func(url string) ([]byte, error) {
+		return gm.cm.Request(url, core.RequestGET, nil)
+	})
 }
 
 // GovProposalMonitor defines a monitor responsible for monitoring new
@@ -80,18 +158,17 @@ type GovProposalMonitor struct {
 }
 
 // NewGovProposalMonitor returns a reference to a new GovProposalMonitor.
-func NewGovProposalMonitor(logger core.Logger, cfg config.Config, name, memo string) *GovProposalMonitor {
-	return &GovProposalMonitor{newBaseGovMonitor(logger, cfg, name, memo)}
+func NewGovProposalMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *GovProposalMonitor {
+	return &GovProposalMonitor{newBaseGovMonitor(logger, cfg, deps, name, memo)}
 }
 
 // Exec implements the Monitor interface. It will attempt to fetch new
 // governance proposals. Upon success, the raw response body and an ID that is
 // the SHA256 of the response body will be returned and an error otherwise.
 func (gpm *GovProposalMonitor) Exec() (resp, id []byte, err error) {
-	url := fmt.Sprintf("%s/gov/proposals?status=%s", gpm.cm.Next(), govProposalStatusNew)
 	gpm.logger.Info("monitoring for new governance proposals")
 
-	resp, proposals, err := gpm.getProposals(url)
+	resp, proposals, err := gpm.getProposals(gpm.cm.Next(), gov.StatusDepositPeriod)
 	if err != nil {
 		gpm.logger.Errorf("failed to monitor for new governance proposals: %v", err)
 		return nil, nil, errors.Wrap(err, "failed to monitor for new governance proposals")
@@ -116,8 +193,8 @@ type GovVotingMonitor struct {
 }
 
 // NewGovVotingMonitor returns a reference to a new GovVotingMonitor.
-func NewGovVotingMonitor(logger core.Logger, cfg config.Config, name, memo string) *GovVotingMonitor {
-	return &GovVotingMonitor{newBaseGovMonitor(logger, cfg, name, memo)}
+func NewGovVotingMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *GovVotingMonitor {
+	return &GovVotingMonitor{newBaseGovMonitor(logger, cfg, deps, name, memo)}
 }
 
 // Exec implements the Monitor interface. It will attempt to fetch governance
@@ -125,10 +202,9 @@ func NewGovVotingMonitor(logger core.Logger, cfg config.Config, name, memo strin
 // and an ID that is the SHA256 of the response body will be returned and an
 // error otherwise.
 func (gvm *GovVotingMonitor) Exec() (resp, id []byte, err error) {
-	url := fmt.Sprintf("%s/gov/proposals?status=%s", gvm.cm.Next(), govProposalStatusVoting)
 	gvm.logger.Info("monitoring for active governance proposals")
 
-	resp, proposals, err := gvm.getProposals(url)
+	resp, proposals, err := gvm.getProposals(gvm.cm.Next(), gov.StatusVotingPeriod)
 	if err != nil {
 		gvm.logger.Errorf("failed to monitor for active governance proposals: %v", err)
 		return nil, nil, errors.Wrap(err, "failed to monitor for active governance proposals")
@@ -146,3 +222,506 @@ func (gvm *GovVotingMonitor) Exec() (resp, id []byte, err error) {
 	return resp, id, nil
 
 }
+
+// MissingVote defines a structure for containing filtered validators that have
+// not yet voted on an active governance proposal along with the reminder
+// threshold the proposal's remaining voting time has crossed.
+type MissingVote struct {
+	ProposalID      int64    `json:"proposal_id"`
+	Title           string   `json:"title"`
+	VotingEndHeight int64    `json:"voting_end_height"`
+	Reminder        string   `json:"reminder"`
+	Validators      []string `json:"validators"`
+}
+
+// GovVoteReminderMonitor defines a monitor responsible for reminding filtered
+// validators to vote on active governance proposals before voting ends.
+type GovVoteReminderMonitor struct {
+	*baseGovMonitor
+	filters      *ValidatorFilters
+	votingPeriod int64
+	reminders    []time.Duration
+}
+
+// NewGovVoteReminderMonitor returns a reference to a new
+// GovVoteReminderMonitor. Reminders are configured in hours before the end of
+// a proposal's voting period.
+func NewGovVoteReminderMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *GovVoteReminderMonitor {
+	return &GovVoteReminderMonitor{
+		baseGovMonitor: newBaseGovMonitor(logger, cfg, deps, name, memo),
+		filters:        deps.Filters,
+		votingPeriod:   cfg.Governance.VotingPeriod,
+		reminders:      hoursToThresholds(cfg.Governance.VoteReminders),
+	}
+}
+
+// Exec implements the Monitor interface. It will attempt to fetch governance
+// proposals that are in the voting stage and check if each filtered validator
+// has voted on them. Since the voting period is defined in blocks, the time
+// remaining is estimated from the average block time. Any proposal that has
+// crossed a reminder threshold and has missing votes is serialized and an ID
+// that is the SHA256 of said encoding will be returned and an error otherwise.
+func (gvrm *GovVoteReminderMonitor) Exec() (resp, id []byte, err error) {
+	client := gvrm.cm.Next()
+	gvrm.logger.Info("monitoring for active governance proposals missing votes")
+
+	_, proposals, err := gvrm.getProposals(client, gov.StatusVotingPeriod)
+	if err != nil {
+		gvrm.logger.Errorf("failed to monitor for active governance proposals: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to monitor for active governance proposals")
+	}
+
+	if len(proposals) == 0 {
+		return nil, nil, errors.New("no proposals returned")
+	}
+
+	latest, blockTime, err := estimateBlockTime(gvrm.cm, gvrm.api, client)
+	if err != nil {
+		gvrm.logger.Errorf("failed to estimate block time: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to estimate block time")
+	}
+
+	filter := gvrm.filters.All()
+
+	var missingVotes []MissingVote
+
+	for _, proposal := range proposals {
+		var votingEndTime time.Time
+		if gp, ok := proposal.(*gatewayProposal); ok {
+			votingEndTime = gp.votingEndTime
+		}
+
+		endHeight, remaining := estimatePeriodEnd(
+			proposal.GetVotingStartBlock(), gvrm.votingPeriod, votingEndTime, latest, blockTime,
+		)
+
+		reminder, ok := crossedThreshold(remaining, gvrm.reminders)
+		if !ok {
+			continue
+		}
+
+		votes, err := gvrm.getVotes(client, proposal.GetProposalID())
+		if err != nil {
+			gvrm.logger.Errorf("failed to get votes for proposal %d: %v", proposal.GetProposalID(), err)
+			return nil, nil, errors.Wrap(err, "failed to get proposal votes")
+		}
+
+		voters := make(map[string]struct{}, len(votes))
+		for _, vote := range votes {
+			voters[gvrm.api.Operator(vote.Voter)] = struct{}{}
+		}
+
+		var nonVoters []string
+		for _, validatorFilter := range filter {
+			if _, ok := voters[validatorFilter.Operator]; !ok {
+				nonVoters = append(nonVoters, validatorFilter.Operator)
+			}
+		}
+
+		if len(nonVoters) != 0 {
+			missingVotes = append(missingVotes, MissingVote{
+				ProposalID:      proposal.GetProposalID(),
+				Title:           proposal.GetTitle(),
+				VotingEndHeight: endHeight,
+				Reminder:        reminder.String(),
+				Validators:      nonVoters,
+			})
+		}
+	}
+
+	if len(missingVotes) == 0 {
+		return nil, nil, errors.New("no proposals missing votes from filtered validators")
+	}
+
+	raw, err := wire.MarshalJSONIndent(gvrm.codec, missingVotes)
+	if err != nil {
+		gvrm.logger.Errorf("failed to serialize missing votes: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to serialize missing votes")
+	}
+
+	rawHash := sha256.Sum256(raw)
+	id = rawHash[:]
+
+	return raw, id, nil
+}
+
+// ProposalOutcome defines a structure for containing the outcome of a watched
+// governance proposal. The tally result is only included for proposals that
+// have finished voting and the deposit end height only for proposals that are
+// about to fail their deposit period.
+type ProposalOutcome struct {
+	ProposalID       int64            `json:"proposal_id"`
+	Title            string           `json:"title"`
+	Outcome          string           `json:"outcome"`
+	TotalDeposit     sdk.Coins        `json:"total_deposit"`
+	TallyResult      *gov.TallyResult `json:"tally_result,omitempty"`
+	DepositEndHeight int64            `json:"deposit_end_height,omitempty"`
+}
+
+// GovOutcomeMonitor defines a monitor responsible for tracking governance
+// proposals through their deposit and voting periods and reporting their final
+// outcome. It also warns when a watched proposal is about to fail its deposit
+// period if deposit warnings are enabled.
+//
+// NOTE: Watched proposals are kept in memory, so outcomes of proposals that
+// completed while Titan was not running are not reported.
+type GovOutcomeMonitor struct {
+	*baseGovMonitor
+
+	mu      sync.Mutex
+	watched map[int64]gov.Proposal
+
+	maxDepositPeriod int64
+	minDeposit       sdk.Coins
+	depositWarning   time.Duration
+}
+
+// NewGovOutcomeMonitor returns a reference to a new GovOutcomeMonitor. The
+// configuration is assumed to have been validated.
+func NewGovOutcomeMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *GovOutcomeMonitor {
+	minDeposit, _ := sdk.ParseCoins(cfg.Governance.MinDeposit)
+
+	return &GovOutcomeMonitor{
+		baseGovMonitor:   newBaseGovMonitor(logger, cfg, deps, name, memo),
+		watched:          make(map[int64]gov.Proposal),
+		maxDepositPeriod: cfg.Governance.MaxDepositPeriod,
+		minDeposit:       minDeposit,
+		depositWarning:   time.Duration(cfg.Governance.DepositWarning) * time.Hour,
+	}
+}
+
+// Exec implements the Monitor interface. It will attempt to fetch all
+// governance proposals and compare them against the proposals being watched.
+// Watched proposals that have passed, been rejected or were removed after
+// failing their deposit period are reported along with proposals that are
+// about to fail their deposit period. Upon success, the serialized outcomes and
+// an ID that is the SHA256 of said encoding will be returned and an error
+// otherwise.
+func (gom *GovOutcomeMonitor) Exec() (resp, id []byte, err error) {
+	client := gom.cm.Next()
+	gom.logger.Info("monitoring for governance proposal outcomes")
+
+	_, proposals, err := gom.getProposals(client, proposalStatusAll)
+	if err != nil {
+		gom.logger.Errorf("failed to monitor for governance proposal outcomes: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to monitor for governance proposal outcomes")
+	}
+
+	gom.mu.Lock()
+	defer gom.mu.Unlock()
+
+	outcomes := gom.trackProposals(client, proposals)
+
+	depositOutcomes, err := gom.depositOutcomes(client, proposals)
+	if err != nil {
+		// deposit warnings are best effort and should not block final outcomes
+		gom.logger.Errorf("failed to check proposal deposit periods: %v", err)
+	}
+
+	outcomes = append(outcomes, depositOutcomes...)
+
+	if len(outcomes) == 0 {
+		return nil, nil, errors.New("no proposal outcomes")
+	}
+
+	raw, err := wire.MarshalJSONIndent(gom.codec, outcomes)
+	if err != nil {
+		gom.logger.Errorf("failed to serialize proposal outcomes: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to serialize proposal outcomes")
+	}
+
+	rawHash := sha256.Sum256(raw)
+	id = rawHash[:]
+
+	return raw, id, nil
+}
+
+// trackProposals updates the set of watched proposals and returns the final
+// outcomes of any watched proposal that has finished. It must be called with
+// the monitor's lock held.
+func (gom *GovOutcomeMonitor) trackProposals(client string, proposals []gov.Proposal) (outcomes []ProposalOutcome) {
+	current := make(map[int64]bool, len(proposals))
+
+	for _, proposal := range proposals {
+		current[proposal.GetProposalID()] = true
+
+		if outcome := gom.trackProposal(proposal); outcome != nil {
+			outcomes = append(outcomes, *outcome)
+		}
+	}
+
+	// Proposals that fail to reach the minimum deposit are deleted. As a client
+	// may respond with an incomplete list of proposals (e.g. it is behind), a
+	// watched proposal that is no longer returned is only considered deleted
+	// once querying it directly confirms that it does not exist.
+	for proposalID, proposal := range gom.watched {
+		if current[proposalID] {
+			continue
+		}
+
+		latest, err := gom.getProposal(client, proposalID)
+		if err != nil {
+			gom.logger.Errorf("failed to get watched proposal %d: %v", proposalID, err)
+			continue
+		}
+
+		if latest != nil {
+			if outcome := gom.trackProposal(latest); outcome != nil {
+				outcomes = append(outcomes, *outcome)
+			}
+
+			continue
+		}
+
+		if proposal.GetStatus() == gov.StatusDepositPeriod {
+			outcomes = append(outcomes, ProposalOutcome{
+				ProposalID:   proposalID,
+				Title:        proposal.GetTitle(),
+				Outcome:      ProposalOutcomeDepositFailed,
+				TotalDeposit: proposal.GetTotalDeposit(),
+			})
+		}
+
+		delete(gom.watched, proposalID)
+	}
+
+	sort.Slice(outcomes, func(i, j int) bool { return outcomes[i].ProposalID < outcomes[j].ProposalID })
+	return outcomes
+}
+
+// trackProposal watches a proposal in its deposit or voting period and returns
+// the final outcome of a watched proposal that has finished. Nil is returned
+// if there is no final outcome. It must be called with the monitor's lock
+// held.
+func (gom *GovOutcomeMonitor) trackProposal(proposal gov.Proposal) *ProposalOutcome {
+	proposalID := proposal.GetProposalID()
+
+	switch proposal.GetStatus() {
+	case gov.StatusDepositPeriod, gov.StatusVotingPeriod:
+		gom.watched[proposalID] = proposal
+
+	case gov.StatusPassed, gov.StatusRejected:
+		if _, ok := gom.watched[proposalID]; !ok {
+			return nil
+		}
+
+		delete(gom.watched, proposalID)
+
+		outcome := ProposalOutcomeRejected
+		if proposal.GetStatus() == gov.StatusPassed {
+			outcome = ProposalOutcomePassed
+		}
+
+		tally := proposal.GetTallyResult()
+		return &ProposalOutcome{
+			ProposalID:   proposalID,
+			Title:        proposal.GetTitle(),
+			Outcome:      outcome,
+			TotalDeposit: proposal.GetTotalDeposit(),
+			TallyResult:  &tally,
+		}
+	}
+
+	return nil
+}
+
+// depositOutcomes returns proposals in their deposit period that have not yet
+// reached the minimum deposit and whose deposit period ends within the deposit
+// warning window. Nothing is returned if deposit warnings are disabled.
+func (gom *GovOutcomeMonitor) depositOutcomes(client string, proposals []gov.Proposal) ([]ProposalOutcome, error) {
+	if gom.depositWarning == 0 {
+		return nil, nil
+	}
+
+	var pending []gov.Proposal
+	for _, proposal := range proposals {
+		if proposal.GetStatus() == gov.StatusDepositPeriod && !proposal.GetTotalDeposit().IsGTE(gom.minDeposit) {
+			pending = append(pending, proposal)
+		}
+	}
+
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	latest, blockTime, err := estimateBlockTime(gom.cm, gom.api, client)
+	if err != nil {
+		return nil, err
+	}
+
+	var outcomes []ProposalOutcome
+
+	for _, proposal := range pending {
+		var depositEndTime time.Time
+		if gp, ok := proposal.(*gatewayProposal); ok {
+			depositEndTime = gp.depositEndTime
+		}
+
+		endHeight, remaining := estimatePeriodEnd(
+			proposal.GetSubmitBlock(), gom.maxDepositPeriod, depositEndTime, latest, blockTime,
+		)
+
+		if remaining >= 0 && remaining <= gom.depositWarning {
+			outcomes = append(outcomes, ProposalOutcome{
+				ProposalID:       proposal.GetProposalID(),
+				Title:            proposal.GetTitle(),
+				Outcome:          ProposalOutcomeDepositEnding,
+				TotalDeposit:     proposal.GetTotalDeposit(),
+				DepositEndHeight: endHeight,
+			})
+		}
+	}
+
+	return outcomes, nil
+}
+
+// UpgradeCountdown defines a structure for containing a planned software
+// upgrade along with the countdown threshold the estimated time until the
+// upgrade height has crossed.
+type UpgradeCountdown struct {
+	ProposalID int64  `json:"proposal_id"`
+	Title      string `json:"title"`
+	Status     string `json:"status"`
+	Name       string `json:"name"`
+	Height     int64  `json:"height"`
+	Countdown  string `json:"countdown"`
+}
+
+// GovUpgradeMonitor defines a monitor responsible for tracking software
+// upgrade proposals that are in the voting stage or have passed and alerting
+// as the chain approaches the planned upgrade height.
+type GovUpgradeMonitor struct {
+	*baseGovMonitor
+	countdowns []time.Duration
+}
+
+// NewGovUpgradeMonitor returns a reference to a new GovUpgradeMonitor.
+// Countdowns are configured in hours before the upgrade height is reached.
+func NewGovUpgradeMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *GovUpgradeMonitor {
+	return &GovUpgradeMonitor{
+		baseGovMonitor: newBaseGovMonitor(logger, cfg, deps, name, memo),
+		countdowns:     hoursToThresholds(cfg.Governance.UpgradeCountdowns),
+	}
+}
+
+// Exec implements the Monitor interface. It will attempt to fetch all software
+// upgrade proposals and extract their planned upgrade height. The time until
+// each upgrade height is estimated from the average block time and any upgrade
+// that has crossed a countdown threshold is serialized and an ID that is the
+// SHA256 of said encoding will be returned and an error otherwise.
+func (gum *GovUpgradeMonitor) Exec() (resp, id []byte, err error) {
+	client := gum.cm.Next()
+	gum.logger.Info("monitoring for upcoming software upgrades")
+
+	_, proposals, err := gum.getProposals(client, proposalStatusAll)
+	if err != nil {
+		gum.logger.Errorf("failed to monitor for software upgrade proposals: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to monitor for software upgrade proposals")
+	}
+
+	var upgrades []UpgradeCountdown
+
+	for _, proposal := range proposals {
+		if proposal.GetProposalType() != gov.ProposalTypeSoftwareUpgrade {
+			continue
+		}
+
+		status := proposal.GetStatus()
+		if status != gov.StatusVotingPeriod && status != gov.StatusPassed {
+			continue
+		}
+
+		name, height, ok := parseUpgradePlan(proposal)
+		if !ok {
+			gum.logger.Debugf("failed to extract upgrade height from proposal %d", proposal.GetProposalID())
+			continue
+		}
+
+		upgrades = append(upgrades, UpgradeCountdown{
+			ProposalID: proposal.GetProposalID(),
+			Title:      proposal.GetTitle(),
+			Status:     status.String(),
+			Name:       name,
+			Height:     height,
+		})
+	}
+
+	if len(upgrades) == 0 {
+		return nil, nil, errors.New("no software upgrade proposals returned")
+	}
+
+	latest, blockTime, err := estimateBlockTime(gum.cm, gum.api, client)
+	if err != nil {
+		gum.logger.Errorf("failed to estimate block time: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to estimate block time")
+	}
+
+	var countdowns []UpgradeCountdown
+
+	for _, upgrade := range upgrades {
+		remaining := time.Duration(upgrade.Height-latest.Block.Height) * blockTime
+
+		if countdown, ok := crossedThreshold(remaining, gum.countdowns); ok {
+			upgrade.Countdown = countdown.String()
+			countdowns = append(countdowns, upgrade)
+		}
+	}
+
+	if len(countdowns) == 0 {
+		return nil, nil, errors.New("no software upgrades within countdown")
+	}
+
+	raw, err := wire.MarshalJSONIndent(gum.codec, countdowns)
+	if err != nil {
+		gum.logger.Errorf("failed to serialize software upgrades: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to serialize software upgrades")
+	}
+
+	rawHash := sha256.Sum256(raw)
+	id = rawHash[:]
+
+	return raw, id, nil
+}
+
+// parseUpgradePlan extracts the planned upgrade name and height from a
+// software upgrade proposal's title and description unless the proposal
+// contains a structured upgrade plan. False is returned if no valid height
+// could be found.
+func parseUpgradePlan(proposal gov.Proposal) (name string, height int64, ok bool) {
+	if gp, ok := proposal.(*gatewayProposal); ok && gp.plan != nil && gp.plan.Height > 0 {
+		return gp.plan.Name, gp.plan.Height, true
+	}
+
+	text := proposal.GetTitle() + "\n" + proposal.GetDescription()
+
+	match := upgradeHeightRegex.FindStringSubmatch(text)
+	if match == nil {
+		return "", 0, false
+	}
+
+	height, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil || height <= 0 {
+		return "", 0, false
+	}
+
+	if match := upgradeNameRegex.FindStringSubmatch(text); match != nil {
+		name = match[1]
+	}
+
+	return name, height, true
+}
+
+// estimatePeriodEnd returns the estimated end height of a proposal's deposit or
+// voting period and the time remaining until it ends. The period is defined in
+// blocks from the given start height unless an end time is given.
+func estimatePeriodEnd(
+	startHeight, period int64, endTime time.Time, latest *ctypes.ResultBlock, blockTime time.Duration,
+) (endHeight int64, remaining time.Duration) {
+	if !endTime.IsZero() {
+		remaining = endTime.Sub(latest.Block.Time)
+		return latest.Block.Height + int64(remaining/blockTime), remaining
+	}
+
+	endHeight = startHeight + period
+	return endHeight, time.Duration(endHeight-latest.Block.Height) * blockTime
+}
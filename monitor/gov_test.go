@@ -2,17 +2,22 @@ package monitor_test
 
 import (
 	"crypto/sha256"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/alexanderbez/titan/config"
 	"github.com/alexanderbez/titan/core"
 	"github.com/alexanderbez/titan/monitor"
 	"github.com/stretchr/testify/require"
 
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/wire"
 	"github.com/cosmos/cosmos-sdk/x/gov"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
 )
 
 func newGovTestCodec() *wire.Codec {
@@ -30,7 +35,7 @@ func newTestGovProposalMonitor(t *testing.T, ts *httptest.Server) *monitor.GovPr
 	cfg := config.Config{Network: config.NetworkConfig{Clients: clients}}
 
 	return monitor.NewGovProposalMonitor(
-		logger, cfg, monitor.GovProposalMonitorName, monitor.GovProposalMonitorMemo,
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.GovProposalMonitorName, monitor.GovProposalMonitorMemo,
 	)
 }
 
@@ -42,7 +47,7 @@ func newTestGovVotingMonitor(t *testing.T, ts *httptest.Server) *monitor.GovVoti
 	cfg := config.Config{Network: config.NetworkConfig{Clients: clients}}
 
 	return monitor.NewGovVotingMonitor(
-		logger, cfg, monitor.GovVotingMonitorName, monitor.GovVotingMonitorMemo,
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.GovVotingMonitorName, monitor.GovVotingMonitorMemo,
 	)
 }
 
@@ -167,3 +172,406 @@ func TestNewActiveProposals(t *testing.T) {
 	require.Equal(t, exID, id)
 	require.Len(t, props, len(proposals))
 }
+
+// newTestBlockTimeResponses returns the responses of a chain at height 200
+// with a block time of five seconds, i.e. of the latest block and of the block
+// at height 100 from which the block time is estimated.
+func newTestBlockTimeResponses() map[string]interface{} {
+	now := time.Now().UTC()
+
+	latestBlock := &ctypes.ResultBlock{Block: tmtypes.MakeBlock(200, nil, nil, nil)}
+	latestBlock.Block.Time = now
+
+	sampleBlock := &ctypes.ResultBlock{Block: tmtypes.MakeBlock(100, nil, nil, nil)}
+	sampleBlock.Block.Time = now.Add(-500 * time.Second)
+
+	return map[string]interface{}{
+		"/blocks/latest": latestBlock,
+		"/blocks/100":    sampleBlock,
+	}
+}
+
+func newTestGovVoteReminderMonitor(t *testing.T, ts *httptest.Server, operator string) *monitor.GovVoteReminderMonitor {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	clients := []string{ts.URL}
+	cfg := config.Config{
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Operator: operator},
+			},
+		},
+		Network: config.NetworkConfig{Clients: clients},
+		Governance: config.Governance{
+			VotingPeriod:  300,
+			VoteReminders: []uint{72, 24, 2},
+		},
+	}
+
+	return monitor.NewGovVoteReminderMonitor(
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.GovVoteReminderMonitorName, monitor.GovVoteReminderMonitorMemo,
+	)
+}
+
+func newTestGovVoteReminderServer(t *testing.T, votes []gov.Vote) *httptest.Server {
+	codec := newGovTestCodec()
+	ctypes.RegisterAmino(codec)
+
+	proposals := []gov.Proposal{
+		&gov.TextProposal{
+			ProposalID:       1,
+			Title:            "test text proposal",
+			Description:      "test text proposal",
+			ProposalType:     gov.ProposalTypeText,
+			Status:           gov.StatusVotingPeriod,
+			TallyResult:      gov.EmptyTallyResult(),
+			VotingStartBlock: 100,
+		},
+	}
+
+	// the voting period ends at height 400
+	responses := newTestBlockTimeResponses()
+	responses["/gov/proposals"] = proposals
+	responses["/gov/proposals/1/votes"] = votes
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		raw, err := codec.MarshalJSON(res)
+		require.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+}
+
+func TestNoMissingVotes(t *testing.T) {
+	voter, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	votes := []gov.Vote{
+		gov.Vote{Voter: voter, ProposalID: 1, Option: gov.OptionYes},
+	}
+
+	ts := newTestGovVoteReminderServer(t, votes)
+	defer ts.Close()
+
+	gvrm := newTestGovVoteReminderMonitor(t, ts, voter.String())
+
+	resp, id, err := gvrm.Exec()
+	require.Error(t, err)
+	require.Nil(t, resp)
+	require.Nil(t, id)
+}
+
+func TestMissingVotes(t *testing.T) {
+	codec := newGovTestCodec()
+
+	voter, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	ts := newTestGovVoteReminderServer(t, []gov.Vote{})
+	defer ts.Close()
+
+	gvrm := newTestGovVoteReminderMonitor(t, ts, voter.String())
+
+	resp, id, err := gvrm.Exec()
+	require.NoError(t, err)
+
+	var missingVotes []monitor.MissingVote
+	err = codec.UnmarshalJSON(resp, &missingVotes)
+	require.NoError(t, err)
+
+	rawHash := sha256.Sum256(resp)
+	exID := rawHash[:]
+
+	require.Equal(t, exID, id)
+	require.Len(t, missingVotes, 1)
+	require.Equal(t, int64(400), missingVotes[0].VotingEndHeight)
+	require.Equal(t, (2 * time.Hour).String(), missingVotes[0].Reminder)
+	require.Equal(t, []string{voter.String()}, missingVotes[0].Validators)
+}
+
+func newTestGovOutcomeMonitor(t *testing.T, ts *httptest.Server, depositWarning uint) *monitor.GovOutcomeMonitor {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	clients := []string{ts.URL}
+	cfg := config.Config{
+		Network: config.NetworkConfig{Clients: clients},
+		Governance: config.Governance{
+			MaxDepositPeriod: 300,
+			MinDeposit:       "10steak",
+			DepositWarning:   depositWarning,
+		},
+	}
+
+	return monitor.NewGovOutcomeMonitor(
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.GovOutcomeMonitorName, monitor.GovOutcomeMonitorMemo,
+	)
+}
+
+func TestProposalOutcomes(t *testing.T) {
+	codec := newGovTestCodec()
+
+	proposals := []gov.Proposal{
+		&gov.TextProposal{
+			ProposalID:   1,
+			Title:        "test voting proposal",
+			ProposalType: gov.ProposalTypeText,
+			Status:       gov.StatusVotingPeriod,
+			TallyResult:  gov.EmptyTallyResult(),
+		},
+		&gov.TextProposal{
+			ProposalID:   2,
+			Title:        "test deposit proposal",
+			ProposalType: gov.ProposalTypeText,
+			Status:       gov.StatusDepositPeriod,
+			TallyResult:  gov.EmptyTallyResult(),
+		},
+		&gov.TextProposal{
+			ProposalID:   3,
+			Title:        "test unwatched proposal",
+			ProposalType: gov.ProposalTypeText,
+			Status:       gov.StatusRejected,
+			TallyResult:  gov.EmptyTallyResult(),
+		},
+	}
+
+	// the list of proposals may be incomplete while a single proposal is
+	// always queried from all proposals
+	listed := proposals
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := interface{}(listed)
+
+		if r.URL.Path != "/gov/proposals" {
+			res = nil
+
+			for _, proposal := range proposals {
+				if r.URL.Path == fmt.Sprintf("/gov/proposals/%d", proposal.GetProposalID()) {
+					res = proposal
+				}
+			}
+
+			if res == nil {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("proposalID does not exist"))
+				return
+			}
+		}
+
+		raw, err := codec.MarshalJSON(res)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+	defer ts.Close()
+
+	gom := newTestGovOutcomeMonitor(t, ts, 0)
+
+	// initial proposals are only watched
+	resp, id, err := gom.Exec()
+	require.Error(t, err)
+	require.Nil(t, resp)
+	require.Nil(t, id)
+
+	// proposals missing from an incomplete list are not considered deleted
+	listed = nil
+
+	resp, id, err = gom.Exec()
+	require.Error(t, err)
+	require.Nil(t, resp)
+	require.Nil(t, id)
+
+	// the voting proposal passes and the deposit proposal is removed
+	proposals[0].SetStatus(gov.StatusPassed)
+	proposals = proposals[:1]
+	listed = proposals
+
+	resp, id, err = gom.Exec()
+	require.NoError(t, err)
+
+	var outcomes []monitor.ProposalOutcome
+	err = codec.UnmarshalJSON(resp, &outcomes)
+	require.NoError(t, err)
+
+	rawHash := sha256.Sum256(resp)
+	exID := rawHash[:]
+
+	require.Equal(t, exID, id)
+	require.Len(t, outcomes, 2)
+	require.Equal(t, monitor.ProposalOutcomePassed, outcomes[0].Outcome)
+	require.NotNil(t, outcomes[0].TallyResult)
+	require.Equal(t, monitor.ProposalOutcomeDepositFailed, outcomes[1].Outcome)
+
+	// outcomes are only reported once
+	resp, id, err = gom.Exec()
+	require.Error(t, err)
+	require.Nil(t, resp)
+	require.Nil(t, id)
+}
+
+func TestProposalDepositEnding(t *testing.T) {
+	codec := newGovTestCodec()
+	ctypes.RegisterAmino(codec)
+
+	proposals := []gov.Proposal{
+		&gov.TextProposal{
+			ProposalID:   1,
+			Title:        "test deposit proposal",
+			ProposalType: gov.ProposalTypeText,
+			Status:       gov.StatusDepositPeriod,
+			TallyResult:  gov.EmptyTallyResult(),
+			SubmitBlock:  100,
+			TotalDeposit: sdk.Coins{sdk.NewInt64Coin("steak", 5)},
+		},
+	}
+
+	// the deposit period ends at height 400
+	responses := newTestBlockTimeResponses()
+	responses["/gov/proposals"] = proposals
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := codec.MarshalJSON(responses[r.URL.Path])
+		require.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+	defer ts.Close()
+
+	gom := newTestGovOutcomeMonitor(t, ts, 1)
+
+	resp, id, err := gom.Exec()
+	require.NoError(t, err)
+
+	var outcomes []monitor.ProposalOutcome
+	err = codec.UnmarshalJSON(resp, &outcomes)
+	require.NoError(t, err)
+
+	rawHash := sha256.Sum256(resp)
+	exID := rawHash[:]
+
+	require.Equal(t, exID, id)
+	require.Len(t, outcomes, 1)
+	require.Equal(t, monitor.ProposalOutcomeDepositEnding, outcomes[0].Outcome)
+	require.Equal(t, int64(400), outcomes[0].DepositEndHeight)
+}
+
+func newTestGovUpgradeMonitor(t *testing.T, ts *httptest.Server) *monitor.GovUpgradeMonitor {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	clients := []string{ts.URL}
+	cfg := config.Config{
+		Network: config.NetworkConfig{Clients: clients},
+		Governance: config.Governance{
+			UpgradeCountdowns: []uint{24, 6, 1},
+		},
+	}
+
+	return monitor.NewGovUpgradeMonitor(
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.GovUpgradeMonitorName, monitor.GovUpgradeMonitorMemo,
+	)
+}
+
+func newTestGovUpgradeServer(t *testing.T, proposals []gov.Proposal) *httptest.Server {
+	codec := newGovTestCodec()
+	ctypes.RegisterAmino(codec)
+
+	responses := newTestBlockTimeResponses()
+	responses["/gov/proposals"] = proposals
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := codec.MarshalJSON(responses[r.URL.Path])
+		require.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+}
+
+func TestNoUpgradeCountdowns(t *testing.T) {
+	proposals := []gov.Proposal{
+		&gov.TextProposal{
+			ProposalID:   1,
+			Title:        "test text proposal",
+			Description:  "height: 300",
+			ProposalType: gov.ProposalTypeText,
+			Status:       gov.StatusPassed,
+			TallyResult:  gov.EmptyTallyResult(),
+		},
+		&gov.TextProposal{
+			ProposalID:   2,
+			Title:        "test distant upgrade proposal",
+			Description:  "height: 100000",
+			ProposalType: gov.ProposalTypeSoftwareUpgrade,
+			Status:       gov.StatusPassed,
+			TallyResult:  gov.EmptyTallyResult(),
+		},
+		&gov.TextProposal{
+			ProposalID:   3,
+			Title:        "test upgrade proposal without a height",
+			Description:  "Upgrade to name: v0.25.0 not before height 300",
+			ProposalType: gov.ProposalTypeSoftwareUpgrade,
+			Status:       gov.StatusPassed,
+			TallyResult:  gov.EmptyTallyResult(),
+		},
+	}
+
+	ts := newTestGovUpgradeServer(t, proposals)
+	defer ts.Close()
+
+	gum := newTestGovUpgradeMonitor(t, ts)
+
+	resp, id, err := gum.Exec()
+	require.Error(t, err)
+	require.Nil(t, resp)
+	require.Nil(t, id)
+}
+
+func TestUpgradeCountdowns(t *testing.T) {
+	codec := newGovTestCodec()
+
+	proposals := []gov.Proposal{
+		&gov.TextProposal{
+			ProposalID:   1,
+			Title:        "test upgrade proposal",
+			Description:  "Upgrade to name: v0.25.0 at height = 300",
+			ProposalType: gov.ProposalTypeSoftwareUpgrade,
+			Status:       gov.StatusPassed,
+			TallyResult:  gov.EmptyTallyResult(),
+		},
+	}
+
+	ts := newTestGovUpgradeServer(t, proposals)
+	defer ts.Close()
+
+	gum := newTestGovUpgradeMonitor(t, ts)
+
+	resp, id, err := gum.Exec()
+	require.NoError(t, err)
+
+	var countdowns []monitor.UpgradeCountdown
+	err = codec.UnmarshalJSON(resp, &countdowns)
+	require.NoError(t, err)
+
+	rawHash := sha256.Sum256(resp)
+	exID := rawHash[:]
+
+	require.Equal(t, exID, id)
+	require.Len(t, countdowns, 1)
+	require.Equal(t, "v0.25.0", countdowns[0].Name)
+	require.Equal(t, int64(300), countdowns[0].Height)
+	require.Equal(t, time.Hour.String(), countdowns[0].Countdown)
+}
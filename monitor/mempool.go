@@ -0,0 +1,180 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	"github.com/pkg/errors"
+
+	"github.com/cosmos/cosmos-sdk/wire"
+)
+
+var (
+	_ Monitor = (*MempoolMonitor)(nil)
+)
+
+// Mempool monitor alert related constants.
+const (
+	MempoolMonitorMemo = "Saturated Node Mempools"
+	MempoolMonitorName = "mempool/saturation"
+)
+
+type (
+	// MempoolMonitor defines a monitor responsible for monitoring the mempool of
+	// each configured Tendermint RPC client. An alert is triggered when a node's
+	// number of unconfirmed transactions or their total size in bytes exceeds
+	// the configured maximum for a number of consecutive executions.
+	//
+	// NOTE: Older Tendermint nodes do not report the total size of their
+	// mempool, in which case only the number of transactions is checked.
+	MempoolMonitor struct {
+		codec  *wire.Codec
+		logger core.Logger
+
+		cm             *core.ClientManager
+		maxTxs         int64
+		maxBytes       int64
+		sustainedPolls uint
+
+		polls map[string]uint
+		since map[string]time.Time
+
+		name string
+		memo string
+	}
+
+	// MempoolSaturation defines a structure for containing the mempool size of
+	// a node that has exceeded the configured maximum for a number of
+	// consecutive polls.
+	MempoolSaturation struct {
+		Node           string    `json:"node"`
+		Txs            int64     `json:"txs"`
+		Bytes          int64     `json:"bytes"`
+		Polls          uint      `json:"polls"`
+		SaturatedSince time.Time `json:"saturated_since"`
+	}
+
+	// unconfirmedTxs defines the number of unconfirmed transactions and their
+	// total size in bytes as returned by the Tendermint RPC endpoint.
+	unconfirmedTxs struct {
+		N          int64 `json:"n_txs"`
+		TotalBytes int64 `json:"total_bytes"`
+	}
+)
+
+// NewMempoolMonitor returns a reference to a new MempoolMonitor.
+func NewMempoolMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *MempoolMonitor {
+	// the monitor is a no-op without any Tendermint RPC clients
+	return &MempoolMonitor{
+		codec:          wire.NewCodec(),
+		logger:         logger.With("module", name),
+		cm:             deps.RPCClients,
+		maxTxs:         cfg.Mempool.MaxTxs,
+		maxBytes:       cfg.Mempool.MaxBytes,
+		sustainedPolls: cfg.Mempool.SustainedPolls,
+		polls:          make(map[string]uint),
+		since:          make(map[string]time.Time),
+		name:           name,
+		memo:           memo,
+	}
+}
+
+// Name implements the Monitor interface. It returns the monitor's name.
+func (mm *MempoolMonitor) Name() string { return mm.name }
+
+// Memo implements the Monitor interface. It returns the monitor's memo.
+func (mm *MempoolMonitor) Memo() string { return mm.memo }
+
+// Exec implements the Monitor interface. It attempts to fetch the number of
+// unconfirmed transactions of every Tendermint RPC client that is not excluded
+// (e.g. for serving a different chain). Upon success, the
+// serialized encoding of the nodes whose mempool has been saturated for the
+// configured number of consecutive polls and an ID that is the SHA256 of the
+// nodes and the time since which they have been saturated will be returned and
+// an error otherwise.
+func (mm *MempoolMonitor) Exec() (resp, id []byte, err error) {
+	if mm.cm == nil {
+		return nil, nil, errors.New("no RPC clients configured")
+	}
+
+	mm.logger.Info("monitoring for saturated mempools")
+
+	var saturations []MempoolSaturation
+	var saturationIDs []string
+
+	for _, node := range mm.cm.Included() {
+		txs, err := mm.getUnconfirmedTxs(fmt.Sprintf("%s/num_unconfirmed_txs", node))
+		if err != nil {
+			// a node that cannot be reached does not affect its saturation count
+			mm.logger.Errorf("failed to get unconfirmed txs of %s: %v", node, err)
+			continue
+		}
+
+		if !mm.saturated(txs) {
+			delete(mm.polls, node)
+			delete(mm.since, node)
+			continue
+		}
+
+		if mm.polls[node] == 0 {
+			mm.since[node] = time.Now().UTC()
+		}
+
+		mm.polls[node]++
+
+		if mm.polls[node] < mm.sustainedPolls {
+			continue
+		}
+
+		saturations = append(saturations, MempoolSaturation{
+			Node:           node,
+			Txs:            txs.N,
+			Bytes:          txs.TotalBytes,
+			Polls:          mm.polls[node],
+			SaturatedSince: mm.since[node],
+		})
+
+		saturationIDs = append(saturationIDs, fmt.Sprintf("%s/%d", node, mm.since[node].UnixNano()))
+	}
+
+	if len(saturations) == 0 {
+		return nil, nil, errors.New("no saturated mempools found")
+	}
+
+	raw, err := wire.MarshalJSONIndent(mm.codec, saturations)
+	if err != nil {
+		mm.logger.Errorf("failed to serialize saturated mempools: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to serialize saturated mempools")
+	}
+
+	// The mempool size and number of polls change at each execution, so the ID
+	// only reflects the time since which nodes have been saturated to alert
+	// once per node and saturation.
+	rawHash := sha256.Sum256([]byte(fmt.Sprintf("%v", saturationIDs)))
+	id = rawHash[:]
+
+	return raw, id, nil
+}
+
+// saturated returns true if the number of unconfirmed transactions or their
+// total size exceeds the respective non-zero maximum.
+func (mm *MempoolMonitor) saturated(txs *unconfirmedTxs) bool {
+	return (mm.maxTxs > 0 && txs.N > mm.maxTxs) || (mm.maxBytes > 0 && txs.TotalBytes > mm.maxBytes)
+}
+
+func (mm *MempoolMonitor) getUnconfirmedTxs(url string) (*unconfirmedTxs, error) {
+	result, err := fetchRPCResult(mm.cm, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var txs unconfirmedTxs
+	if err := mm.codec.UnmarshalJSON(result, &txs); err != nil {
+		return nil, err
+	}
+
+	return &txs, nil
+}
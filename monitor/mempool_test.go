@@ -0,0 +1,108 @@
+package monitor_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	"github.com/alexanderbez/titan/monitor"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMempoolMonitor(t *testing.T, cfg config.Config) *monitor.MempoolMonitor {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	return monitor.NewMempoolMonitor(
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.MempoolMonitorName, monitor.MempoolMonitorMemo,
+	)
+}
+
+func newTestMempoolServer(t *testing.T, sizes *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/num_unconfirmed_txs", r.URL.Path)
+
+		result := (*sizes)[0]
+		*sizes = (*sizes)[1:]
+
+		_, err := fmt.Fprintf(w, `{"jsonrpc":"2.0","id":"","result":%s}`, result)
+		require.NoError(t, err)
+	}))
+}
+
+func TestMempoolSaturation(t *testing.T) {
+	codec := newSlashingTestCodec()
+
+	sizes1 := []string{
+		`{"n_txs":"6000","txs":null}`,
+		`{"n_txs":"6000","txs":null}`,
+		`{"n_txs":"6000","txs":null}`,
+		`{"n_txs":"6000","txs":null}`,
+		`{"n_txs":"10","txs":null}`,
+	}
+
+	// a node reporting the total size of its mempool
+	sizes2 := []string{
+		`{"n_txs":"10","total_bytes":"20000000","txs":null}`,
+		`{"n_txs":"10","total_bytes":"100","txs":null}`,
+		`{"n_txs":"10","total_bytes":"20000000","txs":null}`,
+		`{"n_txs":"10","total_bytes":"20000000","txs":null}`,
+		`{"n_txs":"10","total_bytes":"100","txs":null}`,
+	}
+
+	ts1 := newTestMempoolServer(t, &sizes1)
+	defer ts1.Close()
+
+	ts2 := newTestMempoolServer(t, &sizes2)
+	defer ts2.Close()
+
+	cfg := config.Config{
+		Network: config.NetworkConfig{RPCClients: []string{ts1.URL, ts2.URL}},
+		Mempool: config.Mempool{MaxTxs: 5000, MaxBytes: 10000000, SustainedPolls: 2},
+	}
+
+	mm := newTestMempoolMonitor(t, cfg)
+
+	// the first node is not yet saturated for long enough
+	_, _, err := mm.Exec()
+	require.Error(t, err)
+
+	res, id, err := mm.Exec()
+	require.NoError(t, err)
+	require.NotNil(t, id)
+
+	var saturations []monitor.MempoolSaturation
+	require.NoError(t, codec.UnmarshalJSON(res, &saturations))
+	require.Len(t, saturations, 1)
+	require.Equal(t, ts1.URL, saturations[0].Node)
+	require.Equal(t, int64(6000), saturations[0].Txs)
+	require.Equal(t, uint(2), saturations[0].Polls)
+
+	// the second node was reset in between saturated polls
+	_, nextID, err := mm.Exec()
+	require.NoError(t, err)
+	require.Equal(t, id, nextID)
+
+	res, nextID, err = mm.Exec()
+	require.NoError(t, err)
+	require.NotEqual(t, id, nextID)
+
+	require.NoError(t, codec.UnmarshalJSON(res, &saturations))
+	require.Len(t, saturations, 2)
+	require.Equal(t, ts2.URL, saturations[1].Node)
+	require.Equal(t, int64(20000000), saturations[1].Bytes)
+	require.Equal(t, uint(2), saturations[1].Polls)
+
+	_, _, err = mm.Exec()
+	require.Error(t, err)
+}
+
+func TestMempoolNoRPCClients(t *testing.T) {
+	mm := newTestMempoolMonitor(t, config.Config{Mempool: config.Mempool{MaxTxs: 5000}})
+
+	_, _, err := mm.Exec()
+	require.Error(t, err)
+}
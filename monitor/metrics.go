@@ -0,0 +1,131 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+)
+
+// MetricsCollector defines a collector of the metrics of a chain that do not
+// depend on the enabled monitors, i.e. the height of the latest block and the
+// missed blocks, jailed status and voting power of every filtered validator.
+// Metrics are collected in a go-routine every poll interval once started.
+type MetricsCollector struct {
+	api                chainAPI
+	logger             core.Logger
+	filters            *ValidatorFilters
+	cm                 *core.ClientManager
+	chainID            string
+	signedBlocksWindow int64
+	interval           time.Duration
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// NewMetricsCollector returns a reference to a new MetricsCollector querying
+// the LCD clients through the given client manager if not nil. Missed blocks
+// are only collected if a signed blocks window is configured.
+func NewMetricsCollector(cfg config.Config, logger core.Logger, clients *core.ClientManager) *MetricsCollector {
+	if clients == nil {
+		clients = core.NewClientManager(cfg.Network.Clients)
+	}
+
+	return &MetricsCollector{
+		api:                newChainAPI(cfg),
+		logger:             logger.With("module", "metrics"),
+		filters:            NewValidatorFilters(cfg.Filters.Validators),
+		cm:                 clients,
+		chainID:            cfg.ChainID,
+		signedBlocksWindow: cfg.Slashing.SignedBlocksWindow,
+		interval:           time.Duration(cfg.PollInterval) * time.Second,
+		quit:               make(chan struct{}),
+		done:               make(chan struct{}),
+	}
+}
+
+// Start collects all metrics once and then every poll interval in a
+// go-routine until the collector is stopped.
+func (mc *MetricsCollector) Start() {
+	go func() {
+		defer close(mc.done)
+
+		ticker := time.NewTicker(mc.interval)
+		defer ticker.Stop()
+
+		for {
+			mc.Collect()
+
+			select {
+			case <-mc.quit:
+				return
+
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the collector and waits for it to exit.
+func (mc *MetricsCollector) Stop() {
+	close(mc.quit)
+	<-mc.done
+}
+
+// Collect collects all metrics once. Metrics that cannot be collected keep
+// their previous value and errors are logged.
+func (mc *MetricsCollector) Collect() {
+	metrics := core.DefaultMetrics()
+	client := mc.cm.Next()
+
+	block, err := fetchBlock(mc.cm, mc.api, client)
+	if err != nil {
+		mc.logger.Errorf("failed to get latest block: %v", err)
+	} else {
+		metrics.ObserveLatestHeight(mc.chainID, block.Block.Height)
+	}
+
+	filter := mc.filters.All()
+	if len(filter) == 0 {
+		return
+	}
+
+	vals, err := fetchValidators(mc.cm, mc.api, client)
+	if err != nil {
+		mc.logger.Errorf("failed to get all validators: %v", err)
+		return
+	}
+
+	filtersMap := make(map[string]struct{}, len(filter))
+	for _, validatorFilter := range filter {
+		filtersMap[validatorFilter.Operator] = struct{}{}
+	}
+
+	for _, val := range vals {
+		operator := mc.api.Operator(val.Owner)
+		if _, ok := filtersMap[operator]; !ok {
+			continue
+		}
+
+		power, _ := val.Tokens.Float64()
+		metrics.ValidatorVotingPower.WithLabelValues(mc.chainID, operator).Set(power)
+
+		signingInfo, err := fetchSigningInfo(mc.cm, mc.api, client, val)
+		if err != nil {
+			mc.logger.Errorf("failed to get signing info for validator %s: %v", operator, err)
+			continue
+		}
+
+		if validatorJailed(val, signingInfo) {
+			metrics.ValidatorJailed.WithLabelValues(mc.chainID, operator).Set(1)
+		} else {
+			metrics.ValidatorJailed.WithLabelValues(mc.chainID, operator).Set(0)
+		}
+
+		if mc.signedBlocksWindow != 0 {
+			missed := missedBlocks(signingInfo, mc.signedBlocksWindow)
+			metrics.ValidatorMissedBlocks.WithLabelValues(mc.chainID, operator).Set(float64(missed))
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package monitor_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	"github.com/alexanderbez/titan/monitor"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/slashing"
+	"github.com/cosmos/cosmos-sdk/x/stake"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// scrapeMetrics returns the exposition of all recorded Prometheus metrics.
+func scrapeMetrics(t *testing.T) string {
+	rec := httptest.NewRecorder()
+	core.DefaultMetrics().Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	return rec.Body.String()
+}
+
+func TestMetricsCollector(t *testing.T) {
+	codec := newSlashingTestCodec()
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	operator, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	val := stake.NewValidator(operator, ed25519.GenPrivKey().PubKey(), stake.Description{})
+	val.Tokens = sdk.NewRat(100)
+
+	bechVal, err := val.Bech32Validator()
+	require.NoError(t, err)
+
+	// the validator is not revoked but jailed until a later time
+	signingInfo := slashing.ValidatorSigningInfo{
+		IndexOffset:         50,
+		SignedBlocksCounter: 40,
+		JailedUntil:         time.Now().Add(time.Hour),
+	}
+
+	responses := map[string]interface{}{
+		"/blocks/latest":                           &ctypes.ResultBlock{Block: tmtypes.MakeBlock(7, nil, &tmtypes.Commit{}, nil)},
+		"/stake/validators":                        []stake.BechValidator{bechVal},
+		"/slashing/signing_info/" + bechVal.PubKey: signingInfo,
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := codec.MarshalJSON(responses[r.URL.Path])
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+	defer ts.Close()
+
+	// the metrics are collected without any enabled monitor
+	cfg := config.Config{
+		ChainID: "metrics-chain",
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Operator: operator.String()},
+			},
+		},
+		Network:  config.NetworkConfig{Clients: []string{ts.URL}},
+		Slashing: config.Slashing{SignedBlocksWindow: 100},
+	}
+
+	monitor.NewMetricsCollector(cfg, logger, nil).Collect()
+
+	metrics := scrapeMetrics(t)
+	require.Contains(t, metrics, `titan_chain_latest_block_height{chain_id="metrics-chain"} 7`)
+	require.Contains(t, metrics, fmt.Sprintf(`titan_validator_missed_blocks{chain_id="metrics-chain",validator="%s"} 10`, operator))
+	require.Contains(t, metrics, fmt.Sprintf(`titan_validator_jailed{chain_id="metrics-chain",validator="%s"} 1`, operator))
+	require.Contains(t, metrics, fmt.Sprintf(`titan_validator_voting_power{chain_id="metrics-chain",validator="%s"} 100`, operator))
+}
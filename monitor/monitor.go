@@ -13,52 +13,210 @@ type Monitor interface {
 	Exec() (resp, id []byte, err error)
 }
 
+// PollObserver defines an interface for monitors that are notified of the start
+// of every poll. Polls are numbered sequentially by the manager.
+type PollObserver interface {
+	BeginPoll(poll uint64)
+}
+
+// Deps defines the dependencies shared by all monitors of a chain. It must be
+// created by NewDeps.
+type Deps struct {
+	// Filters defines the validator filters all monitors match against.
+	Filters *ValidatorFilters
+
+	// Clients defines the client manager of the LCD clients.
+	Clients *core.ClientManager
+
+	// RPCClients defines the client manager of the Tendermint RPC clients, which
+	// is nil if none are configured.
+	RPCClients *core.ClientManager
+
+	// Blocks defines the block cache of all monitors acting on blocks.
+	Blocks *BlockCache
+
+	// Events defines the optional event source of monitors consuming events.
+	Events *EventSource
+
+	api chainAPI
+}
+
+// NewDeps returns the dependencies shared by all monitors of a chain. A client
+// manager of the LCD clients is created if the given one is nil. Monitors
+// querying the RPC clients share the client manager of the event source if
+// given.
+func NewDeps(cfg config.Config, clients *core.ClientManager, events *EventSource) Deps {
+	if clients == nil {
+		clients = core.NewClientManager(cfg.Network.Clients)
+	}
+
+	deps := Deps{
+		Filters: NewValidatorFilters(cfg.Filters.Validators),
+		Clients: clients,
+		Blocks:  NewBlockCache(cfg, clients),
+		Events:  events,
+		api:     newChainAPI(cfg),
+	}
+
+	if events != nil {
+		deps.RPCClients = events.cm
+	} else if len(cfg.Network.RPCClients) != 0 {
+		deps.RPCClients = core.NewClientManager(cfg.Network.RPCClients)
+	}
+
+	return deps
+}
+
+// allMonitors defines the order in which all monitors are created if all
+// monitors are enabled.
+var allMonitors = []string{
+	config.MonitorNewProposals,
+	config.MonitorActiveProposals,
+	config.MonitorMissingVotes,
+	config.MonitorProposalOutcomes,
+	config.MonitorSoftwareUpgrades,
+	config.MonitorConsensusKeyChanges,
+	config.MonitorMissingSignatures,
+	config.MonitorDoubleSigning,
+	config.MonitorNetworkDoubleSigning,
+	config.MonitorLowUptime,
+	config.MonitorConsensusRounds,
+	config.MonitorMissingProposals,
+	config.MonitorMempoolSaturation,
+	config.MonitorClientDisagreements,
+	config.MonitorClientMismatches,
+	config.MonitorJailedValidators,
+	config.MonitorUndelegations,
+	config.MonitorLowSelfDelegation,
+	config.MonitorLowBalances,
+}
+
 // CreateMonitors returns a list of initialized monitors. The exact list of
 // created monitors is based upon the enabled monitors in the provided
-// configuration which is assumed to have been validated.
-func CreateMonitors(cfg config.Config, logger core.Logger) (monitors []Monitor) {
+// configuration which is assumed to have been validated. If all monitors are
+// enabled, monitors missing their required configuration are skipped with a
+// warning. All monitors share the dependencies created by NewDeps from the
+// given LCD client manager and event source, either of which may be nil.
+func CreateMonitors(cfg config.Config, logger core.Logger, clients *core.ClientManager, events *EventSource) (monitors []Monitor) {
+	deps := NewDeps(cfg, clients, events)
+
 	gpm := NewGovProposalMonitor(
-		logger, cfg, GovProposalMonitorName, GovProposalMonitorMemo,
+		logger, cfg, deps, GovProposalMonitorName, GovProposalMonitorMemo,
 	)
 
 	gvm := NewGovVotingMonitor(
-		logger, cfg, GovVotingMonitorName, GovVotingMonitorMemo,
+		logger, cfg, deps, GovVotingMonitorName, GovVotingMonitorMemo,
 	)
 
 	msm := NewMissingSigMonitor(
-		logger, cfg, MissingSigMonitorName, MissingSigMonitorMemo,
+		logger, cfg, deps, MissingSigMonitorName, MissingSigMonitorMemo,
 	)
 
 	dsm := NewDoubleSignMonitor(
-		logger, cfg, DoubleSignMonitorName, DoubleSignMonitorMemo,
+		logger, cfg, deps, DoubleSignMonitorName, DoubleSignMonitorMemo,
+	)
+
+	gvrm := NewGovVoteReminderMonitor(
+		logger, cfg, deps, GovVoteReminderMonitorName, GovVoteReminderMonitorMemo,
+	)
+
+	gom := NewGovOutcomeMonitor(
+		logger, cfg, deps, GovOutcomeMonitorName, GovOutcomeMonitorMemo,
+	)
+
+	gum := NewGovUpgradeMonitor(
+		logger, cfg, deps, GovUpgradeMonitorName, GovUpgradeMonitorMemo,
+	)
+
+	ndsm := NewNetworkDoubleSignMonitor(
+		logger, cfg, deps, NetworkDoubleSignMonitorName, NetworkDoubleSignMonitorMemo,
+	)
+
+	um := NewUptimeMonitor(
+		logger, cfg, deps, UptimeMonitorName, UptimeMonitorMemo,
+	)
+
+	ckm := NewConsensusKeyMonitor(
+		logger, cfg, deps, ConsensusKeyMonitorName, ConsensusKeyMonitorMemo,
 	)
 
 	jvm := NewJailedValidatorMonitor(
-		logger, cfg, JailedValidatorMonitorName, JailedValidatorMonitorMemo,
+		logger, cfg, deps, JailedValidatorMonitorName, JailedValidatorMonitorMemo,
+	)
+
+	udm := NewUndelegationMonitor(
+		logger, cfg, deps, UndelegationMonitorName, UndelegationMonitorMemo,
+	)
+
+	sdm := NewSelfDelegationMonitor(
+		logger, cfg, deps, SelfDelegationMonitorName, SelfDelegationMonitorMemo,
+	)
+
+	crm := NewConsensusRoundMonitor(
+		logger, cfg, deps, ConsensusRoundMonitorName, ConsensusRoundMonitorMemo,
+	)
+
+	pm := NewProposerMonitor(
+		logger, cfg, deps, ProposerMonitorName, ProposerMonitorMemo,
+	)
+
+	mpm := NewMempoolMonitor(
+		logger, cfg, deps, MempoolMonitorName, MempoolMonitorMemo,
+	)
+
+	cdm := NewClientDisagreementMonitor(
+		logger, cfg, deps, ClientDisagreementMonitorName, ClientDisagreementMonitorMemo,
 	)
 
-	// cfg.Monitors is assumed to have a valid list of enabled monitors
-	for _, monitor := range cfg.Monitors {
-		switch monitor {
-		case config.MonitorAll:
-			return []Monitor{gpm, gvm, msm, dsm, jvm}
+	cmm := NewClientMismatchMonitor(
+		logger, cfg, deps, ClientMismatchMonitorName, ClientMismatchMonitorMemo,
+	)
 
-		case config.MonitorNewProposals:
-			monitors = append(monitors, gpm)
+	lbm := NewLowBalanceMonitor(
+		logger, cfg, deps, LowBalanceMonitorName, LowBalanceMonitorMemo,
+	)
 
-		case config.MonitorActiveProposals:
-			monitors = append(monitors, gvm)
+	byName := map[string]Monitor{
+		config.MonitorNewProposals:         gpm,
+		config.MonitorActiveProposals:      gvm,
+		config.MonitorMissingVotes:         gvrm,
+		config.MonitorProposalOutcomes:     gom,
+		config.MonitorSoftwareUpgrades:     gum,
+		config.MonitorConsensusKeyChanges:  ckm,
+		config.MonitorMissingSignatures:    msm,
+		config.MonitorDoubleSigning:        dsm,
+		config.MonitorNetworkDoubleSigning: ndsm,
+		config.MonitorLowUptime:            um,
+		config.MonitorConsensusRounds:      crm,
+		config.MonitorMissingProposals:     pm,
+		config.MonitorMempoolSaturation:    mpm,
+		config.MonitorClientDisagreements:  cdm,
+		config.MonitorClientMismatches:     cmm,
+		config.MonitorJailedValidators:     jvm,
+		config.MonitorUndelegations:        udm,
+		config.MonitorLowSelfDelegation:    sdm,
+		config.MonitorLowBalances:          lbm,
+	}
 
-		case config.MonitorJailedValidators:
-			monitors = append(monitors, jvm)
+	// cfg.Monitors is assumed to have a valid list of enabled monitors where
+	// explicitly enabled monitors have their required configuration
+	names := cfg.Monitors
+	if len(names) == 1 && names[0] == config.MonitorAll {
+		names = nil
 
-		case config.MonitorDoubleSigning:
-			monitors = append(monitors, dsm)
+		for _, name := range allMonitors {
+			if err := cfg.CheckMonitor(name); err != nil {
+				logger.Warnf("skipping monitor %s: %v", name, err)
+				continue
+			}
 
-		case config.MonitorMissingSignatures:
-			monitors = append(monitors, msm)
+			names = append(names, name)
 		}
 	}
 
+	for _, name := range names {
+		monitors = append(monitors, byName[name])
+	}
+
 	return monitors
 }
@@ -0,0 +1,148 @@
+package monitor
+
+import (
+	"crypto/sha256"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	"github.com/pkg/errors"
+
+	"github.com/cosmos/cosmos-sdk/wire"
+)
+
+var (
+	_ Monitor = (*ClientDisagreementMonitor)(nil)
+	_ Monitor = (*ClientMismatchMonitor)(nil)
+)
+
+// Network monitor alert related constants.
+const (
+	ClientDisagreementMonitorMemo = "LCD Clients Disagreeing With the Majority"
+	ClientDisagreementMonitorName = "network/clientDisagreement"
+	ClientMismatchMonitorMemo     = "Clients Serving a Different Chain"
+	ClientMismatchMonitorName     = "network/clientMismatch"
+)
+
+// ClientDisagreementMonitor defines a monitor responsible for monitoring LCD
+// clients that respond differently than the majority of clients to quorum
+// reads, which may indicate a fork or a compromised node. Quorum reads are
+// performed by other monitors through the shared client manager, so the
+// monitor never alerts if no quorum is configured.
+type ClientDisagreementMonitor struct {
+	codec  *wire.Codec
+	logger core.Logger
+	cm     *core.ClientManager
+
+	name string
+	memo string
+}
+
+// NewClientDisagreementMonitor returns a reference to a new
+// ClientDisagreementMonitor.
+func NewClientDisagreementMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *ClientDisagreementMonitor {
+	return &ClientDisagreementMonitor{
+		codec:  wire.NewCodec(),
+		logger: logger.With("module", name),
+		cm:     deps.Clients,
+		name:   name,
+		memo:   memo,
+	}
+}
+
+// Name implements the Monitor interface. It returns the monitor's name.
+func (cdm *ClientDisagreementMonitor) Name() string { return cdm.name }
+
+// Memo implements the Monitor interface. It returns the monitor's memo.
+func (cdm *ClientDisagreementMonitor) Memo() string { return cdm.memo }
+
+// Exec implements the Monitor interface. It retrieves all disagreements
+// between LCD clients recorded by quorum reads since the last execution. Upon
+// success, the serialized encoding of the disagreements and an ID that is the
+// SHA256 of said encoding will be returned and an error otherwise.
+func (cdm *ClientDisagreementMonitor) Exec() (resp, id []byte, err error) {
+	cdm.logger.Info("monitoring for disagreeing LCD clients")
+
+	disagreements := cdm.cm.Disagreements()
+	if len(disagreements) == 0 {
+		return nil, nil, errors.New("no disagreeing clients found")
+	}
+
+	raw, err := wire.MarshalJSONIndent(cdm.codec, disagreements)
+	if err != nil {
+		cdm.logger.Errorf("failed to serialize client disagreements: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to serialize client disagreements")
+	}
+
+	rawHash := sha256.Sum256(raw)
+	id = rawHash[:]
+
+	return raw, id, nil
+}
+
+// ClientMismatchMonitor defines a monitor responsible for monitoring LCD and
+// Tendermint RPC clients excluded for not serving the expected chain (e.g. a
+// client pointing at a testnet). Clients are verified by the shared client
+// managers, so the monitor never alerts if no expected chain ID or genesis hash
+// is configured. Clients that have not been verified yet are not alerted on.
+type ClientMismatchMonitor struct {
+	codec  *wire.Codec
+	logger core.Logger
+	cm     *core.ClientManager
+	rpcCM  *core.ClientManager
+
+	name string
+	memo string
+}
+
+// NewClientMismatchMonitor returns a reference to a new ClientMismatchMonitor.
+func NewClientMismatchMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *ClientMismatchMonitor {
+	return &ClientMismatchMonitor{
+		codec:  wire.NewCodec(),
+		logger: logger.With("module", name),
+		cm:     deps.Clients,
+		rpcCM:  deps.RPCClients,
+		name:   name,
+		memo:   memo,
+	}
+}
+
+// Name implements the Monitor interface. It returns the monitor's name.
+func (cmm *ClientMismatchMonitor) Name() string { return cmm.name }
+
+// Memo implements the Monitor interface. It returns the monitor's memo.
+func (cmm *ClientMismatchMonitor) Memo() string { return cmm.memo }
+
+// Exec implements the Monitor interface. It retrieves all LCD and RPC clients
+// that are currently excluded for not serving the expected chain. Upon
+// success, the serialized encoding of the exclusions and an ID that is the
+// SHA256 of said encoding will be returned and an error otherwise.
+func (cmm *ClientMismatchMonitor) Exec() (resp, id []byte, err error) {
+	cmm.logger.Info("monitoring for clients serving a different chain")
+
+	all := cmm.cm.Exclusions()
+	if cmm.rpcCM != nil {
+		all = append(all, cmm.rpcCM.Exclusions()...)
+	}
+
+	var exclusions []core.Exclusion
+	for _, exclusion := range all {
+		if exclusion.Reason != core.UnverifiedReason {
+			exclusions = append(exclusions, exclusion)
+		}
+	}
+
+	if len(exclusions) == 0 {
+		return nil, nil, errors.New("no mismatching clients found")
+	}
+
+	raw, err := wire.MarshalJSONIndent(cmm.codec, exclusions)
+	if err != nil {
+		cmm.logger.Errorf("failed to serialize client exclusions: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to serialize client exclusions")
+	}
+
+	rawHash := sha256.Sum256(raw)
+	id = rawHash[:]
+
+	return raw, id, nil
+}
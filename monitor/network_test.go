@@ -0,0 +1,346 @@
+package monitor_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/alexanderbez/titan/config"
+	"github.com/alexanderbez/titan/core"
+	"github.com/alexanderbez/titan/monitor"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/stake"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/p2p"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func newTestBlockServer(t *testing.T, block *ctypes.ResultBlock) *httptest.Server {
+	raw, err := newSlashingTestCodec().MarshalJSON(block)
+	require.NoError(t, err)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+}
+
+func TestClientDisagreements(t *testing.T) {
+	codec := newSlashingTestCodec()
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	pubKey := ed25519.GenPrivKey().PubKey()
+
+	commit := &tmtypes.Commit{
+		Precommits: []*tmtypes.Vote{
+			&tmtypes.Vote{ValidatorAddress: pubKey.Address()},
+		},
+	}
+
+	// the lying client claims the filtered validator missed the block
+	honest := &ctypes.ResultBlock{Block: tmtypes.MakeBlock(10, nil, commit, nil)}
+	lying := &ctypes.ResultBlock{Block: tmtypes.MakeBlock(10, nil, &tmtypes.Commit{}, nil)}
+
+	ts1 := newTestBlockServer(t, lying)
+	defer ts1.Close()
+
+	ts2 := newTestBlockServer(t, honest)
+	defer ts2.Close()
+
+	ts3 := newTestBlockServer(t, honest)
+	defer ts3.Close()
+
+	cfg := config.Config{
+		Monitors: []string{config.MonitorMissingSignatures, config.MonitorClientDisagreements},
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Address: pubKey.Address().String()},
+			},
+		},
+		Network: config.NetworkConfig{
+			Clients: []string{ts1.URL, ts2.URL, ts3.URL},
+			Quorum:  2,
+		},
+	}
+
+	clients := monitor.NewLCDClientManager(cfg, logger)
+	defer clients.Stop()
+
+	monitors := monitor.CreateMonitors(cfg, logger, clients, nil)
+	require.Len(t, monitors, 2)
+
+	// the block the majority agrees on is used
+	_, _, err = monitors[0].Exec()
+	require.Error(t, err)
+
+	resp, id, err := monitors[1].Exec()
+	require.NoError(t, err)
+	require.NotNil(t, id)
+
+	var disagreements []core.Disagreement
+	require.NoError(t, codec.UnmarshalJSON(resp, &disagreements))
+
+	expected := []core.Disagreement{
+		{Path: "/blocks/10", Majority: []string{ts2.URL, ts3.URL}, Dissenting: []string{ts1.URL}},
+	}
+	require.Equal(t, expected, disagreements)
+
+	// disagreements are only reported once
+	_, _, err = monitors[1].Exec()
+	require.Error(t, err)
+}
+
+func TestClientDisagreementsValidators(t *testing.T) {
+	codec := newStakingTestCodec()
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	operator, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	newValidatorsServer := func(revoked bool) *httptest.Server {
+		val := stake.NewValidator(operator, nil, stake.Description{})
+		val.Revoked = revoked
+
+		raw, err := codec.MarshalJSON([]stake.Validator{val})
+		require.NoError(t, err)
+
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write(raw)
+		}))
+	}
+
+	// the lying client claims the filtered validator is not jailed
+	ts1 := newValidatorsServer(false)
+	defer ts1.Close()
+
+	ts2 := newValidatorsServer(true)
+	defer ts2.Close()
+
+	ts3 := newValidatorsServer(true)
+	defer ts3.Close()
+
+	cfg := config.Config{
+		Monitors: []string{config.MonitorJailedValidators, config.MonitorClientDisagreements},
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Operator: operator.String()},
+			},
+		},
+		Network: config.NetworkConfig{
+			Clients: []string{ts1.URL, ts2.URL, ts3.URL},
+			Quorum:  2,
+		},
+	}
+
+	clients := monitor.NewLCDClientManager(cfg, logger)
+	defer clients.Stop()
+
+	monitors := monitor.CreateMonitors(cfg, logger, clients, nil)
+	require.Len(t, monitors, 2)
+
+	// the validators the majority agrees on are used
+	_, _, err = monitors[0].Exec()
+	require.NoError(t, err)
+
+	resp, _, err := monitors[1].Exec()
+	require.NoError(t, err)
+
+	var disagreements []core.Disagreement
+	require.NoError(t, codec.UnmarshalJSON(resp, &disagreements))
+
+	expected := []core.Disagreement{
+		{Path: "/stake/validators", Majority: []string{ts2.URL, ts3.URL}, Dissenting: []string{ts1.URL}},
+	}
+	require.Equal(t, expected, disagreements)
+}
+
+func TestClientDisagreementsNoMajority(t *testing.T) {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	ts1 := newTestBlockServer(t, &ctypes.ResultBlock{Block: tmtypes.MakeBlock(10, nil, &tmtypes.Commit{}, nil)})
+	defer ts1.Close()
+
+	ts2 := newTestBlockServer(t, &ctypes.ResultBlock{Block: tmtypes.MakeBlock(10, []tmtypes.Tx{tmtypes.Tx("tx")}, &tmtypes.Commit{}, nil)})
+	defer ts2.Close()
+
+	cfg := config.Config{
+		Monitors: []string{config.MonitorMissingSignatures, config.MonitorClientDisagreements},
+		Network: config.NetworkConfig{
+			Clients: []string{ts1.URL, ts2.URL},
+			Quorum:  2,
+		},
+	}
+
+	clients := monitor.NewLCDClientManager(cfg, logger)
+	defer clients.Stop()
+
+	monitors := monitor.CreateMonitors(cfg, logger, clients, nil)
+	require.Len(t, monitors, 2)
+
+	// no block is acted upon without a majority
+	_, _, err = monitors[0].Exec()
+	require.Error(t, err)
+
+	_, _, err = monitors[1].Exec()
+	require.NoError(t, err)
+}
+
+func TestClientMismatches(t *testing.T) {
+	codec := newSlashingTestCodec()
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	newNodeInfoServer := func(network string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/node_info", r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"network":"` + network + `"}`))
+		}))
+	}
+
+	ts1 := newNodeInfoServer("test-chain")
+	defer ts1.Close()
+
+	ts2 := newNodeInfoServer("other-chain")
+	defer ts2.Close()
+
+	cfg := config.Config{
+		Monitors: []string{config.MonitorClientMismatches},
+		Network: config.NetworkConfig{
+			Clients:         []string{ts1.URL, ts2.URL},
+			ExpectedChainID: "test-chain",
+		},
+	}
+
+	clients := monitor.NewLCDClientManager(cfg, logger)
+	defer clients.Stop()
+
+	// the mismatching client is never used
+	require.Equal(t, []string{ts1.URL}, clients.Healthy())
+	require.Equal(t, ts1.URL, clients.Next())
+	require.Equal(t, ts1.URL, clients.Next())
+
+	monitors := monitor.CreateMonitors(cfg, logger, clients, nil)
+	require.Len(t, monitors, 1)
+
+	resp, id, err := monitors[0].Exec()
+	require.NoError(t, err)
+	require.NotNil(t, id)
+
+	var exclusions []core.Exclusion
+	require.NoError(t, codec.UnmarshalJSON(resp, &exclusions))
+	require.Len(t, exclusions, 1)
+	require.Equal(t, ts2.URL, exclusions[0].Client)
+	require.Contains(t, exclusions[0].Reason, "other-chain")
+}
+
+func TestUnverifiedClients(t *testing.T) {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	var (
+		mu        sync.Mutex
+		reachable bool
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !reachable {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"network":"test-chain"}`))
+	}))
+	defer ts.Close()
+
+	cfg := config.Config{
+		Network: config.NetworkConfig{
+			Clients:         []string{ts.URL},
+			ExpectedChainID: "test-chain",
+			VerifyInterval:  1,
+		},
+	}
+
+	clients := monitor.NewLCDClientManager(cfg, logger)
+	defer clients.Stop()
+
+	// a client unreachable at startup is excluded until it is verified
+	require.Empty(t, clients.Healthy())
+
+	exclusions := clients.Exclusions()
+	require.Len(t, exclusions, 1)
+	require.Equal(t, "unverified", exclusions[0].Reason)
+
+	mu.Lock()
+	reachable = true
+	mu.Unlock()
+
+	waitFor(t, func() bool { return len(clients.Healthy()) == 1 })
+	require.Empty(t, clients.Exclusions())
+}
+
+func TestRPCClientMismatches(t *testing.T) {
+	codec := newSlashingTestCodec()
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	newStatusServer := func(network string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the event subscription of the started event source fails
+			if r.URL.Path != "/status" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			result := ctypes.ResultStatus{NodeInfo: p2p.NodeInfo{Network: network}}
+			resp := rpctypes.NewRPCSuccessResponse(codec, "", result)
+			require.NoError(t, json.NewEncoder(w).Encode(resp))
+		}))
+	}
+
+	ts1 := newStatusServer("test-chain")
+	defer ts1.Close()
+
+	ts2 := newStatusServer("other-chain")
+	defer ts2.Close()
+
+	cfg := config.Config{
+		Monitors: []string{config.MonitorClientMismatches},
+		Network: config.NetworkConfig{
+			RPCClients:      []string{ts1.URL, ts2.URL},
+			ExpectedChainID: "test-chain",
+		},
+	}
+
+	events := monitor.NewEventSource(cfg, logger)
+	events.Start()
+	defer events.Stop()
+
+	monitors := monitor.CreateMonitors(cfg, logger, nil, events)
+	require.Len(t, monitors, 1)
+
+	resp, id, err := monitors[0].Exec()
+	require.NoError(t, err)
+	require.NotNil(t, id)
+
+	var exclusions []core.Exclusion
+	require.NoError(t, codec.UnmarshalJSON(resp, &exclusions))
+	require.Len(t, exclusions, 1)
+	require.Equal(t, ts2.URL, exclusions[0].Client)
+	require.Contains(t, exclusions[0].Reason, "other-chain")
+}
@@ -3,6 +3,8 @@ package monitor
 import (
 	"crypto/sha256"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/alexanderbez/godash"
 	"github.com/alexanderbez/titan/config"
@@ -10,7 +12,9 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/cosmos/cosmos-sdk/wire"
+	"github.com/cosmos/cosmos-sdk/x/slashing"
 	"github.com/cosmos/cosmos-sdk/x/stake"
+	staketypes "github.com/cosmos/cosmos-sdk/x/stake/types"
 
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	tmtypes "github.com/tendermint/tendermint/types"
@@ -19,6 +23,8 @@ import (
 var (
 	_ Monitor = (*MissingSigMonitor)(nil)
 	_ Monitor = (*DoubleSignMonitor)(nil)
+	_ Monitor = (*UptimeMonitor)(nil)
+	_ Monitor = (*NetworkDoubleSignMonitor)(nil)
 )
 
 // Slashing monitor alert related constants.
@@ -27,14 +33,35 @@ const (
 	MissingSigMonitorName = "slashing/missingSig"
 	DoubleSignMonitorMemo = "Discovered Double Signing Validators"
 	DoubleSignMonitorName = "slashing/doubleSign"
+	UptimeMonitorMemo     = "Validators With Low Uptime"
+	UptimeMonitorName     = "slashing/uptime"
+
+	NetworkDoubleSignMonitorMemo = "Network-Wide Double Signing Evidence"
+	NetworkDoubleSignMonitorName = "slashing/networkDoubleSign"
 )
 
+// maxBlockBackfill defines the maximum number of blocks missed by the event
+// source that are fetched from the LCD clients in a single execution.
+const maxBlockBackfill = 100
+
+// uptimeBucketSize defines the size in percentage points of the uptime buckets
+// of the UptimeMonitor's ID, i.e. a validator's uptime has to change buckets to
+// re-trigger an alert.
+const uptimeBucketSize = 10
+
+// defaultNetworkDoubleSignSeverity defines the severity of network-wide double
+// signing alerts when none is configured.
+const defaultNetworkDoubleSignSeverity = "critical"
+
 type (
 	baseSlashingMonitor struct {
-		codec  *wire.Codec
-		logger core.Logger
-		filter []config.ValidatorFilter
-		cm     *core.ClientManager
+		codec   *wire.Codec
+		api     chainAPI
+		logger  core.Logger
+		filters *ValidatorFilters
+		events  *EventSource
+		cm      *core.ClientManager
+		blocks  *BlockCache
 
 		latestHeight int64
 
@@ -43,21 +70,65 @@ type (
 	}
 
 	// MissingSigners defines a structure for containing addresses of validators
-	// that have missed a signature/precommit for a given block height.
+	// that have missed a signature/precommit for a given block height. Any
+	// earlier blocks since the last execution (i.e. received from the event
+	// source) with missing signatures are included as previous blocks.
 	MissingSigners struct {
-		Height         int64    `json:"height"`
-		MissingSigners []string `json:"missing_signers"`
+		Height         int64          `json:"height"`
+		MissingSigners []string       `json:"missing_signers"`
+		PreviousBlocks []BlockSigners `json:"previous_blocks,omitempty"`
 	}
 
 	// DoubleSigners defines a structure for containing addresses of validators
-	// that have double signed for a given block height.
+	// that have double signed for a given block height. Any earlier blocks since
+	// the last execution (i.e. received from the event source) with double
+	// signatures are included as previous blocks.
 	DoubleSigners struct {
-		Height        int64    `json:"height"`
-		DoubleSigners []string `json:"double_signers"`
+		Height         int64          `json:"height"`
+		DoubleSigners  []string       `json:"double_signers"`
+		PreviousBlocks []BlockSigners `json:"previous_blocks,omitempty"`
+	}
+
+	// BlockSigners defines a structure for containing addresses of validators
+	// that have missed or double signed a signature/precommit for a given block
+	// height.
+	BlockSigners struct {
+		Height  int64    `json:"height"`
+		Signers []string `json:"signers"`
+	}
+
+	// DoubleSignEvidence defines a structure for containing duplicate vote
+	// evidence of any validator in the network, resolved to the validator's
+	// operator and moniker when possible.
+	DoubleSignEvidence struct {
+		Address  string `json:"address"`
+		Operator string `json:"operator"`
+		Moniker  string `json:"moniker"`
+		Height   int64  `json:"height"`
+		Filtered bool   `json:"filtered"`
+	}
+
+	// NetworkDoubleSigns defines a structure for containing all duplicate vote
+	// evidence included in a block at a given height.
+	NetworkDoubleSigns struct {
+		Height   int64                `json:"height"`
+		Evidence []DoubleSignEvidence `json:"evidence"`
+	}
+
+	// ValidatorUptime defines a structure for containing the signing info of a
+	// validator whose uptime over the signed blocks window has fallen below
+	// the configured minimum or that is jailed.
+	ValidatorUptime struct {
+		Operator            string    `json:"operator"`
+		Uptime              string    `json:"uptime"`
+		SignedBlocksCounter int64     `json:"signed_blocks_counter"`
+		SignedBlocksWindow  int64     `json:"signed_blocks_window"`
+		Jailed              bool      `json:"jailed"`
+		JailedUntil         time.Time `json:"jailed_until"`
 	}
 )
 
-func newBaseSlashingMonitor(logger core.Logger, cfg config.Config, name, memo string) *baseSlashingMonitor {
+func newBaseSlashingMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *baseSlashingMonitor {
 	logger = logger.With("module", name)
 
 	codec := wire.NewCodec()
@@ -65,30 +136,32 @@ func newBaseSlashingMonitor(logger core.Logger, cfg config.Config, name, memo st
 	ctypes.RegisterAmino(codec)
 
 	return &baseSlashingMonitor{
-		codec:  codec,
-		logger: logger,
-		filter: cfg.Filters.Validators,
-		cm:     core.NewClientManager(cfg.Network.Clients),
-		name:   name,
-		memo:   memo,
+		codec:   codec,
+		api:     deps.api,
+		logger:  logger,
+		filters: deps.Filters,
+		events:  deps.Events,
+		cm:      deps.Clients,
+		blocks:  deps.Blocks,
+		name:    name,
+		memo:    memo,
 	}
 }
 
+// BeginPoll implements the PollObserver interface. It starts the poll of the
+// monitor's block cache.
+func (sm *baseSlashingMonitor) BeginPoll(poll uint64) { sm.blocks.BeginPoll(poll) }
+
 // Name implements the Monitor interface. It returns the monitor's name.
 func (sm *baseSlashingMonitor) Name() string { return sm.name }
 
 // Memo implements the Monitor interface. It returns the monitor's memo.
 func (sm *baseSlashingMonitor) Memo() string { return sm.memo }
 
-func (sm *baseSlashingMonitor) getBlock(url string) (resp []byte, block *ctypes.ResultBlock, err error) {
-	resp, err = core.Request(url, core.RequestGET, nil)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	err = sm.codec.UnmarshalJSON(resp, &block)
+func (sm *baseSlashingMonitor) getLatestBlock() (*ctypes.ResultBlock, error) {
+	block, err := sm.blocks.Latest()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	// An older block could be received if the current client is behind some
@@ -97,10 +170,99 @@ func (sm *baseSlashingMonitor) getBlock(url string) (resp []byte, block *ctypes.
 	if block.Block.Height > sm.latestHeight {
 		sm.latestHeight = block.Block.Height
 	} else {
-		return nil, nil, errors.New("received old block")
+		return nil, errors.New("received old block")
 	}
 
-	return resp, block, nil
+	return block, nil
+}
+
+// nextBlocks returns the blocks to monitor since the last execution. Blocks are
+// taken from the event source while it is connected, where any blocks missed
+// between executions (e.g. while reconnecting) are backfilled from the LCD
+// clients. Otherwise, the latest block is polled from the LCD clients. All
+// blocks are fetched through the shared block cache.
+func (sm *baseSlashingMonitor) nextBlocks() ([]*tmtypes.Block, error) {
+	if sm.events == nil || !sm.events.Connected() {
+		prevHeight := sm.latestHeight
+
+		block, err := sm.getLatestBlock()
+		if err != nil {
+			return nil, err
+		}
+
+		// only act on the block the quorum of clients agrees on
+		if sm.cm.Quorum() > 1 {
+			block, err = sm.blocks.At(block.Block.Height)
+			if err != nil {
+				sm.latestHeight = prevHeight
+				return nil, errors.Wrap(err, "failed to verify latest block")
+			}
+		}
+
+		return []*tmtypes.Block{block.Block}, nil
+	}
+
+	blocks := sm.events.BlocksAfter(sm.latestHeight)
+	if len(blocks) == 0 {
+		return nil, errors.New("no new blocks received")
+	}
+
+	var missed []*tmtypes.Block
+
+	if sm.latestHeight > 0 {
+		end := blocks[0].Height
+		if end-sm.latestHeight > maxBlockBackfill {
+			sm.logger.Errorf(
+				"skipping blocks at heights %d to %d beyond the maximum backfill of %d blocks",
+				sm.latestHeight+maxBlockBackfill, end-1, maxBlockBackfill,
+			)
+
+			end = sm.latestHeight + maxBlockBackfill
+		}
+
+		for height := sm.latestHeight + 1; height < end; height++ {
+			block, err := sm.blocks.At(height)
+			if err != nil {
+				sm.logger.Errorf(
+					"failed to backfill block, skipping blocks at heights %d to %d: %v",
+					height, end-1, err,
+				)
+				break
+			}
+
+			missed = append(missed, block.Block)
+		}
+	}
+
+	// Advance past all received blocks, including any skipped blocks that could
+	// not be backfilled, so they are not attempted again.
+	blocks = append(missed, blocks...)
+	sm.latestHeight = blocks[len(blocks)-1].Height
+
+	return blocks, nil
+}
+
+// duplicateVote returns the address of the equivocating validator and the
+// height of duplicate vote evidence. False is returned for any other evidence.
+func duplicateVote(e tmtypes.Evidence) (address string, height int64, ok bool) {
+	switch e := e.(type) {
+	case *tmtypes.DuplicateVoteEvidence:
+		if e == nil || e.PubKey == nil {
+			return "", 0, false
+		}
+
+		if e.VoteA != nil {
+			height = e.Height()
+		}
+
+		return e.PubKey.Address().String(), height, true
+
+	case *gatewayDuplicateVoteEvidence:
+		return e.address.String(), e.height, true
+
+	default:
+		return "", 0, false
+	}
 }
 
 // MissingSigMonitor defines a monitor responsible for monitoring when filtered
@@ -110,49 +272,66 @@ type MissingSigMonitor struct {
 }
 
 // NewMissingSigMonitor returns a reference to a new MissingSigMonitor.
-func NewMissingSigMonitor(logger core.Logger, cfg config.Config, name, memo string) *MissingSigMonitor {
-	return &MissingSigMonitor{newBaseSlashingMonitor(logger, cfg, name, memo)}
+func NewMissingSigMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *MissingSigMonitor {
+	return &MissingSigMonitor{newBaseSlashingMonitor(logger, cfg, deps, name, memo)}
 }
 
 // Exec implements the Monitor interface. It attempts to fetch validators that
-// have missed signing the latest block based on a given filter of validator
-// addresses. Any matches are serialized and an ID that is the SHA256 of said
-// encoding will be returned and an error otherwise.
+// have missed signing each new block based on a given filter of validator
+// addresses. Any matches of the latest such block, along with those of any
+// previous blocks, are serialized and an ID that is the SHA256 of said encoding
+// will be returned and an error otherwise.
 func (msm *MissingSigMonitor) Exec() (resp, id []byte, err error) {
-	url := fmt.Sprintf("%s/blocks/latest", msm.cm.Next())
-	msm.logger.Info("monitoring for validators that have missed signing the latest block")
+	msm.logger.Info("monitoring for validators that have missed signing new blocks")
 
-	_, block, err := msm.getBlock(url)
+	blocks, err := msm.nextBlocks()
 	if err != nil {
-		msm.logger.Errorf("failed to monitor for the latest block: %v", err)
-		return nil, nil, errors.Wrap(err, "failed to get latest block")
+		msm.logger.Errorf("failed to monitor for new blocks: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to get new blocks")
 	}
 
-	filtersMap := make(map[string]struct{}, len(msm.filter))
-	for _, validatorFilter := range msm.filter {
-		filtersMap[validatorFilter.Address] = struct{}{}
-	}
+	filter := msm.filters.All()
+
+	var missed []BlockSigners
 
-	for _, vote := range block.Block.LastCommit.Precommits {
-		if vote != nil {
-			delete(filtersMap, vote.ValidatorAddress.String())
+	for _, block := range blocks {
+		filtersMap := make(map[string]struct{}, len(filter))
+		for _, validatorFilter := range filter {
+			filtersMap[validatorFilter.Address] = struct{}{}
+		}
+
+		if block.LastCommit != nil {
+			for _, vote := range block.LastCommit.Precommits {
+				if vote != nil {
+					delete(filtersMap, vote.ValidatorAddress.String())
+				}
+			}
 		}
-	}
 
-	// remaining addresses in filters must have missed signing the latest block
-	var missedSigners []string
+		// remaining addresses in filters must have missed signing the block
+		var missedSigners []string
 
-	if err = godash.MapKeys(filtersMap, &missedSigners); err != nil {
-		return nil, nil, errors.Wrap(err, "failed to get missing signers from filter")
+		if err = godash.MapKeys(filtersMap, &missedSigners); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to get missing signers from filter")
+		}
+
+		if len(missedSigners) != 0 {
+			missed = append(missed, BlockSigners{
+				Height:  block.Header.Height - 1,
+				Signers: missedSigners,
+			})
+		}
 	}
 
-	if len(missedSigners) == 0 {
+	if len(missed) == 0 {
 		return nil, nil, errors.New("no validators matching filter returned")
 	}
 
+	latest := missed[len(missed)-1]
 	ms := MissingSigners{
-		Height:         block.Block.Header.Height - 1,
-		MissingSigners: missedSigners,
+		Height:         latest.Height,
+		MissingSigners: latest.Signers,
+		PreviousBlocks: missed[:len(missed)-1],
 	}
 
 	raw, err := wire.MarshalJSONIndent(msm.codec, ms)
@@ -174,51 +353,64 @@ type DoubleSignMonitor struct {
 }
 
 // NewDoubleSignMonitor returns a reference to a new DoubleSignMonitor.
-func NewDoubleSignMonitor(logger core.Logger, cfg config.Config, name, memo string) *DoubleSignMonitor {
-	return &DoubleSignMonitor{newBaseSlashingMonitor(logger, cfg, name, memo)}
+func NewDoubleSignMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *DoubleSignMonitor {
+	return &DoubleSignMonitor{newBaseSlashingMonitor(logger, cfg, deps, name, memo)}
 }
 
 // Exec implements the Monitor interface. It attempts to fetch validators that
-// have double signed the latest block and match against a given filter of
+// have double signed in each new block and match against a given filter of
 // validator addresses. Upon success, the serialized encoding of the filtered
-// validator addresses and an ID that is the SHA256 of said encoding will be
+// validator addresses of the latest such block, along with those of any
+// previous blocks, and an ID that is the SHA256 of said encoding will be
 // returned and an error otherwise.
 func (dsm *DoubleSignMonitor) Exec() (resp, id []byte, err error) {
-	url := fmt.Sprintf("%s/blocks/latest", dsm.cm.Next())
 	dsm.logger.Info("monitoring for validators that have double signed")
 
-	_, block, err := dsm.getBlock(url)
+	blocks, err := dsm.nextBlocks()
 	if err != nil {
-		dsm.logger.Errorf("failed to monitor for the latest block: %v", err)
-		return nil, nil, errors.Wrap(err, "failed to get latest block")
+		dsm.logger.Errorf("failed to monitor for new blocks: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to get new blocks")
 	}
 
-	filtersMap := make(map[string]struct{}, len(dsm.filter))
-	for _, validatorFilter := range dsm.filter {
+	filter := dsm.filters.All()
+
+	filtersMap := make(map[string]struct{}, len(filter))
+	for _, validatorFilter := range filter {
 		filtersMap[validatorFilter.Address] = struct{}{}
 	}
 
-	var byzantineAddrs []string
+	var doubleSigned []BlockSigners
 
-	for _, e := range block.Block.Evidence.Evidence {
-		dve, ok := e.(*tmtypes.DuplicateVoteEvidence)
-		if ok && dve != nil {
-			valAddr := dve.PubKey.Address().String()
+	for _, block := range blocks {
+		var byzantineAddrs []string
 
-			// check the byzantine signer against the filter map of addresses
-			if _, ok := filtersMap[valAddr]; ok {
-				byzantineAddrs = append(byzantineAddrs, valAddr)
+		for _, e := range block.Evidence.Evidence {
+			valAddr, _, ok := duplicateVote(e)
+			if ok {
+				// check the byzantine signer against the filter map of addresses
+				if _, ok := filtersMap[valAddr]; ok {
+					byzantineAddrs = append(byzantineAddrs, valAddr)
+				}
 			}
 		}
+
+		if len(byzantineAddrs) != 0 {
+			doubleSigned = append(doubleSigned, BlockSigners{
+				Height:  block.Header.Height - 1,
+				Signers: byzantineAddrs,
+			})
+		}
 	}
 
-	if len(byzantineAddrs) == 0 {
+	if len(doubleSigned) == 0 {
 		return nil, nil, errors.New("no validators matching filter returned")
 	}
 
+	latest := doubleSigned[len(doubleSigned)-1]
 	ds := DoubleSigners{
-		Height:        block.Block.Header.Height - 1,
-		DoubleSigners: byzantineAddrs,
+		Height:         latest.Height,
+		DoubleSigners:  latest.Signers,
+		PreviousBlocks: doubleSigned[:len(doubleSigned)-1],
 	}
 
 	raw, err := wire.MarshalJSONIndent(dsm.codec, ds)
@@ -232,3 +424,265 @@ func (dsm *DoubleSignMonitor) Exec() (resp, id []byte, err error) {
 
 	return raw, id, nil
 }
+
+// UptimeMonitor defines a monitor responsible for monitoring the uptime of
+// filtered validators over the slashing module's signed blocks window. It
+// gives signal well before a validator is jailed for downtime.
+type UptimeMonitor struct {
+	*baseSlashingMonitor
+	signedBlocksWindow int64
+	minUptime          float64
+}
+
+// NewUptimeMonitor returns a reference to a new UptimeMonitor.
+func NewUptimeMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *UptimeMonitor {
+	return &UptimeMonitor{
+		baseSlashingMonitor: newBaseSlashingMonitor(logger, cfg, deps, name, memo),
+		signedBlocksWindow:  cfg.Slashing.SignedBlocksWindow,
+		minUptime:           cfg.Slashing.MinUptime,
+	}
+}
+
+// Exec implements the Monitor interface. It attempts to fetch the signing info
+// of each filtered validator and compute its uptime over the signed blocks
+// window. Upon success, the serialized encoding of validators with an uptime
+// below the minimum or that are jailed will be returned along with an ID that
+// is the SHA256 of each such validator's operator, jailed status and uptime
+// bucket so that a fluctuating uptime does not re-trigger an alert while a
+// falling one does. An error is returned otherwise.
+//
+// NOTE: Tombstoning is not supported by the slashing module of the current
+// SDK version and is therefore not reported.
+func (um *UptimeMonitor) Exec() (resp, id []byte, err error) {
+	client := um.cm.Next()
+	um.logger.Info("monitoring for validators with low uptime")
+
+	vals, err := fetchValidators(um.cm, um.api, client)
+	if err != nil {
+		um.logger.Errorf("failed to get all validators: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to get all validators")
+	}
+
+	filter := um.filters.All()
+
+	filtersMap := make(map[string]struct{}, len(filter))
+	for _, validatorFilter := range filter {
+		filtersMap[validatorFilter.Operator] = struct{}{}
+	}
+
+	var (
+		uptimes []ValidatorUptime
+		idParts []string
+	)
+
+	for _, val := range vals {
+		operator := um.api.Operator(val.Owner)
+		if _, ok := filtersMap[operator]; !ok {
+			continue
+		}
+
+		signingInfo, err := fetchSigningInfo(um.cm, um.api, client, val)
+		if err != nil {
+			um.logger.Errorf("failed to get signing info for validator %s: %v", operator, err)
+			return nil, nil, errors.Wrap(err, "failed to get signing info")
+		}
+
+		uptime := um.uptime(signingInfo)
+		jailed := validatorJailed(val, signingInfo)
+
+		if uptime < um.minUptime || jailed {
+			uptimes = append(uptimes, ValidatorUptime{
+				Operator:            operator,
+				Uptime:              fmt.Sprintf("%.2f%%", uptime),
+				SignedBlocksCounter: signingInfo.SignedBlocksCounter,
+				SignedBlocksWindow:  um.signedBlocksWindow,
+				Jailed:              jailed,
+				JailedUntil:         signingInfo.JailedUntil,
+			})
+
+			bucket := int(uptime) / uptimeBucketSize
+			idParts = append(idParts, fmt.Sprintf("%s/%t/%d", operator, jailed, bucket))
+		}
+	}
+
+	if len(uptimes) == 0 {
+		return nil, nil, errors.New("no validators matching filter with low uptime")
+	}
+
+	raw, err := wire.MarshalJSONIndent(um.codec, uptimes)
+	if err != nil {
+		um.logger.Errorf("failed to serialize filtered validators: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to serialize filtered validators")
+	}
+
+	rawHash := sha256.Sum256([]byte(strings.Join(idParts, ";")))
+	id = rawHash[:]
+
+	return raw, id, nil
+}
+
+// uptime returns the percentage of blocks signed over the blocks counted in
+// the signed blocks window. A validator that has yet to be counted for any
+// block is considered to have full uptime.
+func (um *UptimeMonitor) uptime(signingInfo slashing.ValidatorSigningInfo) float64 {
+	counted := countedBlocks(signingInfo, um.signedBlocksWindow)
+	if counted <= 0 {
+		return 100
+	}
+
+	return 100 * float64(signingInfo.SignedBlocksCounter) / float64(counted)
+}
+
+// fetchSigningInfo attempts to fetch and decode the signing info of a given
+// validator from a given client using the given client manager.
+func fetchSigningInfo(
+	cm *core.ClientManager, api chainAPI, client string, val staketypes.BechValidator,
+) (slashing.ValidatorSigningInfo, error) {
+
+	path, err := api.SigningInfoPath(val)
+	if err != nil {
+		return slashing.ValidatorSigningInfo{}, err
+	}
+
+	resp, err := cm.Request(client+path, core.RequestGET, nil)
+	if err != nil {
+		return slashing.ValidatorSigningInfo{}, err
+	}
+
+	return api.DecodeSigningInfo(resp)
+}
+
+// validatorJailed returns true if a given validator is revoked or jailed until
+// a time that has yet to pass according to its signing info.
+func validatorJailed(val staketypes.BechValidator, signingInfo slashing.ValidatorSigningInfo) bool {
+	return val.Revoked || signingInfo.JailedUntil.After(time.Now())
+}
+
+// missedBlocks returns the number of blocks missed over the blocks counted in
+// a signed blocks window of a given size.
+func missedBlocks(signingInfo slashing.ValidatorSigningInfo, signedBlocksWindow int64) int64 {
+	if missed := countedBlocks(signingInfo, signedBlocksWindow) - signingInfo.SignedBlocksCounter; missed > 0 {
+		return missed
+	}
+
+	return 0
+}
+
+// countedBlocks returns the number of blocks counted in a signed blocks window
+// of a given size.
+func countedBlocks(signingInfo slashing.ValidatorSigningInfo, signedBlocksWindow int64) int64 {
+	if signingInfo.IndexOffset > signedBlocksWindow {
+		return signedBlocksWindow
+	}
+
+	return signingInfo.IndexOffset
+}
+
+// NetworkDoubleSignMonitor defines a monitor responsible for monitoring for any
+// duplicate vote evidence in the network, regardless of the validator filters.
+// Its memo is prefixed with the configured severity.
+type NetworkDoubleSignMonitor struct {
+	*baseSlashingMonitor
+	severity string
+}
+
+// NewNetworkDoubleSignMonitor returns a reference to a new
+// NetworkDoubleSignMonitor.
+func NewNetworkDoubleSignMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *NetworkDoubleSignMonitor {
+	severity := cfg.Slashing.NetworkDoubleSignSeverity
+	if severity == "" {
+		severity = defaultNetworkDoubleSignSeverity
+	}
+
+	return &NetworkDoubleSignMonitor{
+		baseSlashingMonitor: newBaseSlashingMonitor(logger, cfg, deps, name, memo),
+		severity:            severity,
+	}
+}
+
+// Memo implements the Monitor interface. It returns the monitor's memo
+// prefixed with its severity.
+func (ndsm *NetworkDoubleSignMonitor) Memo() string {
+	return fmt.Sprintf("[%s] %s", strings.ToUpper(ndsm.severity), ndsm.memo)
+}
+
+// Exec implements the Monitor interface. It attempts to fetch all duplicate
+// vote evidence in each new block and resolve each offending consensus address
+// to a validator operator and moniker. Evidence is still reported if the
+// validators cannot be fetched or the offender cannot be resolved. Upon
+// success, the serialized encoding of the evidence and an ID that is the
+// SHA256 of said encoding will be returned and an error otherwise.
+func (ndsm *NetworkDoubleSignMonitor) Exec() (resp, id []byte, err error) {
+	ndsm.logger.Info("monitoring for double signing evidence in the network")
+
+	blocks, err := ndsm.nextBlocks()
+	if err != nil {
+		ndsm.logger.Errorf("failed to monitor for new blocks: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to get new blocks")
+	}
+
+	var doubleSigns []NetworkDoubleSigns
+
+	for _, block := range blocks {
+		var evidence []DoubleSignEvidence
+
+		for _, e := range block.Evidence.Evidence {
+			if address, height, ok := duplicateVote(e); ok {
+				evidence = append(evidence, DoubleSignEvidence{Address: address, Height: height})
+			}
+		}
+
+		if len(evidence) != 0 {
+			doubleSigns = append(doubleSigns, NetworkDoubleSigns{
+				Height:   block.Header.Height,
+				Evidence: evidence,
+			})
+		}
+	}
+
+	if len(doubleSigns) == 0 {
+		return nil, nil, errors.New("no double signing evidence returned")
+	}
+
+	filtersMap := make(map[string]struct{})
+	for _, validatorFilter := range ndsm.filters.All() {
+		filtersMap[validatorFilter.Address] = struct{}{}
+	}
+
+	vals, err := fetchValidators(ndsm.cm, ndsm.api, ndsm.cm.Next())
+	if err != nil {
+		ndsm.logger.Errorf("failed to get all validators: %v", err)
+	}
+
+	valsMap := make(map[string]staketypes.BechValidator, len(vals))
+	for _, val := range vals {
+		pubKey, err := ndsm.api.ConsPubKey(val.PubKey)
+		if err != nil {
+			continue
+		}
+
+		valsMap[pubKey.Address().String()] = val
+	}
+
+	for _, nds := range doubleSigns {
+		for i, e := range nds.Evidence {
+			if val, ok := valsMap[e.Address]; ok {
+				nds.Evidence[i].Operator = ndsm.api.Operator(val.Owner)
+				nds.Evidence[i].Moniker = val.Description.Moniker
+			}
+
+			_, nds.Evidence[i].Filtered = filtersMap[e.Address]
+		}
+	}
+
+	raw, err := wire.MarshalJSONIndent(ndsm.codec, doubleSigns)
+	if err != nil {
+		ndsm.logger.Errorf("failed to serialize double signing evidence: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to serialize double signing evidence")
+	}
+
+	rawHash := sha256.Sum256(raw)
+	id = rawHash[:]
+
+	return raw, id, nil
+}
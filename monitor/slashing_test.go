@@ -5,11 +5,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/alexanderbez/titan/config"
 	"github.com/alexanderbez/titan/core"
 	"github.com/alexanderbez/titan/monitor"
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/wire"
+	"github.com/cosmos/cosmos-sdk/x/slashing"
 	"github.com/cosmos/cosmos-sdk/x/stake"
 	"github.com/stretchr/testify/require"
 	"github.com/tendermint/tendermint/crypto/ed25519"
@@ -30,7 +33,7 @@ func newTestMissingSigMonitor(t *testing.T, cfg config.Config) *monitor.MissingS
 	require.NoError(t, err)
 
 	return monitor.NewMissingSigMonitor(
-		logger, cfg, monitor.MissingSigMonitorName, monitor.MissingSigMonitorMemo,
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.MissingSigMonitorName, monitor.MissingSigMonitorMemo,
 	)
 }
 
@@ -39,7 +42,7 @@ func newTestDoubleSignMonitor(t *testing.T, cfg config.Config) *monitor.DoubleSi
 	require.NoError(t, err)
 
 	return monitor.NewDoubleSignMonitor(
-		logger, cfg, monitor.DoubleSignMonitorName, monitor.DoubleSignMonitorMemo,
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.DoubleSignMonitorName, monitor.DoubleSignMonitorMemo,
 	)
 }
 
@@ -249,3 +252,197 @@ func TestMatchingDoubleSigners(t *testing.T) {
 	require.Equal(t, exID, id)
 	require.Len(t, doubleSigners.DoubleSigners, len(block.Block.Evidence.Evidence))
 }
+
+func newTestUptimeMonitor(t *testing.T, ts *httptest.Server, operator string) *monitor.UptimeMonitor {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	clients := []string{ts.URL}
+	cfg := config.Config{
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Operator: operator},
+			},
+		},
+		Network: config.NetworkConfig{Clients: clients},
+		Slashing: config.Slashing{
+			SignedBlocksWindow: 100,
+			MinUptime:          90,
+		},
+	}
+
+	return monitor.NewUptimeMonitor(
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.UptimeMonitorName, monitor.UptimeMonitorMemo,
+	)
+}
+
+func newTestSigningInfoServer(t *testing.T, signingInfo slashing.ValidatorSigningInfo) (*httptest.Server, sdk.AccAddress) {
+	codec := newSlashingTestCodec()
+
+	operator, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	val, err := stake.NewValidator(operator, ed25519.GenPrivKey().PubKey(), stake.Description{}).Bech32Validator()
+	require.NoError(t, err)
+
+	responses := map[string]interface{}{
+		"/stake/validators":                    []stake.BechValidator{val},
+		"/slashing/signing_info/" + val.PubKey: signingInfo,
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := codec.MarshalJSON(responses[r.URL.Path])
+		require.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+
+	return ts, operator
+}
+
+func TestNoLowUptime(t *testing.T) {
+	signingInfo := slashing.ValidatorSigningInfo{
+		IndexOffset:         500,
+		SignedBlocksCounter: 95,
+		JailedUntil:         time.Unix(0, 0),
+	}
+
+	ts, operator := newTestSigningInfoServer(t, signingInfo)
+	defer ts.Close()
+
+	um := newTestUptimeMonitor(t, ts, operator.String())
+
+	resp, id, err := um.Exec()
+	require.Error(t, err)
+	require.Nil(t, resp)
+	require.Nil(t, id)
+}
+
+func TestLowUptime(t *testing.T) {
+	codec := newSlashingTestCodec()
+
+	signingInfo := slashing.ValidatorSigningInfo{
+		IndexOffset:         50,
+		SignedBlocksCounter: 40,
+		JailedUntil:         time.Unix(0, 0),
+	}
+
+	ts, operator := newTestSigningInfoServer(t, signingInfo)
+	defer ts.Close()
+
+	um := newTestUptimeMonitor(t, ts, operator.String())
+
+	resp, id, err := um.Exec()
+	require.NoError(t, err)
+	require.NotNil(t, id)
+
+	var uptimes []monitor.ValidatorUptime
+	err = codec.UnmarshalJSON(resp, &uptimes)
+	require.NoError(t, err)
+
+	require.Len(t, uptimes, 1)
+	require.Equal(t, operator.String(), uptimes[0].Operator)
+	require.Equal(t, "80.00%", uptimes[0].Uptime)
+	require.False(t, uptimes[0].Jailed)
+
+	// a slightly changed uptime results in the same ID while a falling one
+	// results in a new ID
+	signingInfo.SignedBlocksCounter = 41
+
+	ts2, _ := newTestSigningInfoServer(t, signingInfo)
+	defer ts2.Close()
+
+	_, id2, err := newTestUptimeMonitor(t, ts2, operator.String()).Exec()
+	require.NoError(t, err)
+	require.Equal(t, id, id2)
+
+	signingInfo.SignedBlocksCounter = 25
+
+	ts3, _ := newTestSigningInfoServer(t, signingInfo)
+	defer ts3.Close()
+
+	_, id3, err := newTestUptimeMonitor(t, ts3, operator.String()).Exec()
+	require.NoError(t, err)
+	require.NotEqual(t, id, id3)
+}
+
+func TestNetworkDoubleSigners(t *testing.T) {
+	codec := newSlashingTestCodec()
+
+	opAddr1, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	pubKey1 := ed25519.GenPrivKey().PubKey()
+	pubKey2 := ed25519.GenPrivKey().PubKey()
+
+	block := &ctypes.ResultBlock{
+		Block: tmtypes.MakeBlock(5, nil, nil, []tmtypes.Evidence{
+			&tmtypes.DuplicateVoteEvidence{PubKey: pubKey1, VoteA: &tmtypes.Vote{Height: 3}},
+			&tmtypes.DuplicateVoteEvidence{PubKey: pubKey2, VoteA: &tmtypes.Vote{Height: 4}},
+		}),
+	}
+
+	rawBlock, err := codec.MarshalJSON(block)
+	require.NoError(t, err)
+
+	val, err := stake.NewValidator(opAddr1, pubKey1, stake.Description{Moniker: "titan"}).Bech32Validator()
+	require.NoError(t, err)
+
+	rawVals, err := codec.MarshalJSON([]stake.BechValidator{val})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+
+		if r.URL.Path == "/stake/validators" {
+			w.Write(rawVals)
+		} else {
+			w.Write(rawBlock)
+		}
+	}))
+	defer ts.Close()
+
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	clients := []string{ts.URL}
+	cfg := config.Config{
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Address: pubKey2.Address().String()},
+			},
+		},
+		Network:  config.NetworkConfig{Clients: clients},
+		Slashing: config.Slashing{NetworkDoubleSignSeverity: "warning"},
+	}
+
+	ndsm := monitor.NewNetworkDoubleSignMonitor(
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.NetworkDoubleSignMonitorName, monitor.NetworkDoubleSignMonitorMemo,
+	)
+	require.Equal(t, "[WARNING] "+monitor.NetworkDoubleSignMonitorMemo, ndsm.Memo())
+
+	resp, id, err := ndsm.Exec()
+	require.NoError(t, err)
+
+	var doubleSigns []monitor.NetworkDoubleSigns
+	err = codec.UnmarshalJSON(resp, &doubleSigns)
+	require.NoError(t, err)
+
+	rawHash := sha256.Sum256(resp)
+	exID := rawHash[:]
+
+	require.Equal(t, exID, id)
+	require.Len(t, doubleSigns, 1)
+
+	evidence := doubleSigns[0].Evidence
+	require.Len(t, evidence, 2)
+
+	require.Equal(t, opAddr1.String(), evidence[0].Operator)
+	require.Equal(t, "titan", evidence[0].Moniker)
+	require.Equal(t, int64(3), evidence[0].Height)
+	require.False(t, evidence[0].Filtered)
+
+	require.Empty(t, evidence[1].Operator)
+	require.True(t, evidence[1].Filtered)
+}
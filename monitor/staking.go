@@ -3,49 +3,75 @@ package monitor
 import (
 	"crypto/sha256"
 	"fmt"
+	"math/big"
+	"strings"
 
 	"github.com/pkg/errors"
 
 	"github.com/alexanderbez/titan/config"
 	"github.com/alexanderbez/titan/core"
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/wire"
+	"github.com/cosmos/cosmos-sdk/x/auth"
 	"github.com/cosmos/cosmos-sdk/x/stake"
 	staketypes "github.com/cosmos/cosmos-sdk/x/stake/types"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
 )
 
 var (
 	_ Monitor = (*JailedValidatorMonitor)(nil)
+	_ Monitor = (*ConsensusKeyMonitor)(nil)
+	_ Monitor = (*UndelegationMonitor)(nil)
+	_ Monitor = (*SelfDelegationMonitor)(nil)
 )
 
 // Staking monitor alert related constants.
 const (
 	JailedValidatorMonitorMemo = "New Jailed Validators"
 	JailedValidatorMonitorName = "staking/jailed"
+	ConsensusKeyMonitorMemo    = "Validator Consensus Key Changes"
+	ConsensusKeyMonitorName    = "staking/consensusKey"
+	UndelegationMonitorMemo    = "Large Unbonding Delegations and Redelegations"
+	UndelegationMonitorName    = "staking/undelegation"
+	SelfDelegationMonitorMemo  = "Validators With Low Self-Delegation"
+	SelfDelegationMonitorName  = "staking/selfDelegation"
+)
+
+// Undelegation types.
+const (
+	UndelegationTypeUnbonding    = "unbonding"
+	UndelegationTypeRedelegation = "redelegation"
 )
 
 type baseStakingMonitor struct {
-	codec  *wire.Codec
-	logger core.Logger
-	filter []config.ValidatorFilter
-	cm     *core.ClientManager
-	name   string
-	memo   string
+	codec   *wire.Codec
+	api     chainAPI
+	logger  core.Logger
+	filters *ValidatorFilters
+	cm      *core.ClientManager
+	name    string
+	memo    string
 }
 
-func newBaseStakingMonitor(logger core.Logger, cfg config.Config, name, memo string) *baseStakingMonitor {
+func newBaseStakingMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *baseStakingMonitor {
 	logger = logger.With("module", name)
 
 	codec := wire.NewCodec()
+	sdk.RegisterWire(codec)
+	auth.RegisterWire(codec)
 	stake.RegisterWire(codec)
 	wire.RegisterCrypto(codec)
 
 	return &baseStakingMonitor{
-		codec:  codec,
-		logger: logger,
-		filter: cfg.Filters.Validators,
-		cm:     core.NewClientManager(cfg.Network.Clients),
-		name:   name,
-		memo:   memo,
+		codec:   codec,
+		api:     deps.api,
+		logger:  logger,
+		filters: deps.Filters,
+		cm:      deps.Clients,
+		name:    name,
+		memo:    memo,
 	}
 }
 
@@ -55,20 +81,8 @@ func (sm *baseStakingMonitor) Name() string { return sm.name }
 // Memo implements the Monitor interface. It returns the monitor's memo.
 func (sm *baseStakingMonitor) Memo() string { return sm.memo }
 
-func (sm baseStakingMonitor) getValidators(url string) (resp []byte, vals []staketypes.BechValidator, err error) {
-	resp, err = core.Request(url, core.RequestGET, nil)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// TODO: Update once the SDK version has been updated to support the removal
-	// of the Bech32 serialization.
-	err = sm.codec.UnmarshalJSON(resp, &vals)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	return resp, vals, nil
+func (sm baseStakingMonitor) getValidators(client string) ([]staketypes.BechValidator, error) {
+	return fetchValidators(sm.cm, sm.api, client)
 }
 
 // JailedValidatorMonitor defines a monitor responsible for monitoring when
@@ -79,8 +93,8 @@ type JailedValidatorMonitor struct {
 
 // NewJailedValidatorMonitor returns a reference to a new
 // JailedValidatorMonitor.
-func NewJailedValidatorMonitor(logger core.Logger, cfg config.Config, name, memo string) *JailedValidatorMonitor {
-	return &JailedValidatorMonitor{newBaseStakingMonitor(logger, cfg, name, memo)}
+func NewJailedValidatorMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *JailedValidatorMonitor {
+	return &JailedValidatorMonitor{newBaseStakingMonitor(logger, cfg, deps, name, memo)}
 }
 
 // Exec implements the Monitor interface. It attempts to fetch validators that
@@ -88,24 +102,25 @@ func NewJailedValidatorMonitor(logger core.Logger, cfg config.Config, name, memo
 // success, the serialized encoding of the filtered validators and an ID that
 // is the SHA256 of said encoding will be returned and an error otherwise.
 func (jvm *JailedValidatorMonitor) Exec() (resp, id []byte, err error) {
-	url := fmt.Sprintf("%s/stake/validators", jvm.cm.Next())
 	jvm.logger.Info("monitoring for new jailed validators")
 
-	_, vals, err := jvm.getValidators(url)
+	vals, err := jvm.getValidators(jvm.cm.Next())
 	if err != nil {
 		jvm.logger.Errorf("failed to get all validators: %v", err)
 		return nil, nil, err
 	}
 
-	filtersMap := make(map[string]struct{}, len(jvm.filter))
-	for _, validatorFilter := range jvm.filter {
+	filter := jvm.filters.All()
+
+	filtersMap := make(map[string]struct{}, len(filter))
+	for _, validatorFilter := range filter {
 		filtersMap[validatorFilter.Operator] = struct{}{}
 	}
 
 	// filter validators that are jailed and match the given filter of addresses
 	var filteredVals []staketypes.BechValidator
 	for _, val := range vals {
-		if _, ok := filtersMap[val.Owner.String()]; ok {
+		if _, ok := filtersMap[jvm.api.Operator(val.Owner)]; ok {
 			// TODO: Update once the SDK version has been updated to support the
 			// 'jailed' field.
 			if val.Revoked {
@@ -131,3 +146,538 @@ func (jvm *JailedValidatorMonitor) Exec() (resp, id []byte, err error) {
 
 	return raw, id, nil
 }
+
+// ConsensusKeyChange defines a structure for containing a filtered validator
+// whose current consensus address does not match the configured address.
+type ConsensusKeyChange struct {
+	Operator          string `json:"operator"`
+	ConfiguredAddress string `json:"configured_address"`
+	CurrentAddress    string `json:"current_address"`
+	PubKey            string `json:"pub_key"`
+	Followed          bool   `json:"followed"`
+}
+
+// ConsensusKeyMonitor defines a monitor responsible for monitoring when the
+// consensus key of filtered validators no longer matches the configured
+// consensus address. If enabled, the shared validator filters are updated to
+// follow the new consensus address.
+type ConsensusKeyMonitor struct {
+	*baseStakingMonitor
+	follow bool
+}
+
+// NewConsensusKeyMonitor returns a reference to a new ConsensusKeyMonitor.
+func NewConsensusKeyMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *ConsensusKeyMonitor {
+	return &ConsensusKeyMonitor{
+		baseStakingMonitor: newBaseStakingMonitor(logger, cfg, deps, name, memo),
+		follow:             cfg.Staking.FollowConsensusKey,
+	}
+}
+
+// Exec implements the Monitor interface. It attempts to fetch all validators
+// and resolve the current consensus address of each filtered validator from
+// its consensus public key. Upon success, the serialized encoding of filtered
+// validators whose consensus address has changed and an ID that is the SHA256
+// of said encoding will be returned and an error otherwise.
+func (ckm *ConsensusKeyMonitor) Exec() (resp, id []byte, err error) {
+	ckm.logger.Info("monitoring for validator consensus key changes")
+
+	vals, err := ckm.getValidators(ckm.cm.Next())
+	if err != nil {
+		ckm.logger.Errorf("failed to get all validators: %v", err)
+		return nil, nil, err
+	}
+
+	filtersMap := make(map[string]config.ValidatorFilter)
+	for _, validatorFilter := range ckm.filters.All() {
+		filtersMap[validatorFilter.Operator] = validatorFilter
+	}
+
+	var keyChanges []ConsensusKeyChange
+
+	for _, val := range vals {
+		validatorFilter, ok := filtersMap[ckm.api.Operator(val.Owner)]
+		if !ok {
+			continue
+		}
+
+		pubKey, err := ckm.api.ConsPubKey(val.PubKey)
+		if err != nil {
+			ckm.logger.Errorf("failed to decode consensus public key %s: %v", val.PubKey, err)
+			return nil, nil, errors.Wrap(err, "failed to decode consensus public key")
+		}
+
+		address := pubKey.Address().String()
+		if address == validatorFilter.Address {
+			continue
+		}
+
+		keyChange := ConsensusKeyChange{
+			Operator:          validatorFilter.Operator,
+			ConfiguredAddress: validatorFilter.Address,
+			CurrentAddress:    address,
+			PubKey:            val.PubKey,
+		}
+
+		if ckm.follow {
+			keyChange.Followed = ckm.filters.SetAddress(validatorFilter.Operator, address)
+			ckm.logger.Infof(
+				"following consensus address %s for validator %s", address, validatorFilter.Operator,
+			)
+		}
+
+		keyChanges = append(keyChanges, keyChange)
+	}
+
+	if len(keyChanges) == 0 {
+		return nil, nil, errors.New("no validators matching filter with consensus key changes")
+	}
+
+	raw, err := wire.MarshalJSONIndent(ckm.codec, keyChanges)
+	if err != nil {
+		ckm.logger.Errorf("failed to serialize consensus key changes: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to serialize consensus key changes")
+	}
+
+	rawHash := sha256.Sum256(raw)
+	id = rawHash[:]
+
+	return raw, id, nil
+}
+
+// txInfo defines a transaction as returned by the LCD transaction search.
+type txInfo struct {
+	Hash   cmn.HexBytes           `json:"hash"`
+	Height int64                  `json:"height"`
+	Tx     sdk.Tx                 `json:"tx"`
+	Result abci.ResponseDeliverTx `json:"result"`
+}
+
+// Undelegation defines a structure for containing an unbonding delegation or
+// redelegation from a filtered validator that exceeds the configured maximum.
+type Undelegation struct {
+	TxHash       string         `json:"tx_hash"`
+	Height       int64          `json:"height"`
+	Type         string         `json:"type"`
+	Delegator    sdk.AccAddress `json:"delegator"`
+	Validator    sdk.AccAddress `json:"validator"`
+	DstValidator sdk.AccAddress `json:"dst_validator,omitempty"`
+	Shares       sdk.Rat        `json:"shares"`
+	Tokens       sdk.Rat        `json:"tokens"`
+	Share        string         `json:"share"`
+}
+
+// UndelegationMonitor defines a monitor responsible for monitoring when a
+// single delegator unbonds or redelegates a large amount of tokens from
+// filtered validators. Transactions are taken from the event source while it is
+// connected and searched for via the LCD clients otherwise, where only
+// transactions above the height processed by the last execution in either mode
+// are considered. The first execution starts at the latest block.
+type UndelegationMonitor struct {
+	*baseStakingMonitor
+	events    *EventSource
+	txSeq     uint64
+	height    int64
+	maxAmount int64
+	maxShare  float64
+
+	// transactions received from the event source are Amino encoded and can
+	// only be decoded with the legacy API
+	decodeEvents bool
+}
+
+// NewUndelegationMonitor returns a reference to a new UndelegationMonitor.
+func NewUndelegationMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *UndelegationMonitor {
+	return &UndelegationMonitor{
+		baseStakingMonitor: newBaseStakingMonitor(logger, cfg, deps, name, memo),
+		events:             deps.Events,
+		maxAmount:          cfg.Staking.MaxUndelegationAmount,
+		maxShare:           cfg.Staking.MaxUndelegationShare,
+		decodeEvents:       cfg.Network.LegacyAPI(),
+	}
+}
+
+// searchTxs returns all transactions sourced from a given validator operator.
+func (sm baseStakingMonitor) searchTxs(client, operator string) ([]txInfo, error) {
+	paths, err := sm.api.TxSearchPaths(operator)
+	if err != nil {
+		return nil, err
+	}
+
+	var txs []txInfo
+	for _, path := range paths {
+		resp, err := sm.cm.Request(client+path, core.RequestGET, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		pathTxs, err := sm.api.DecodeTxs(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		txs = append(txs, pathTxs...)
+	}
+
+	return txs, nil
+}
+
+// eventTxs returns all successful transactions received from the event source
+// since the last execution.
+func (um *UndelegationMonitor) eventTxs() []txInfo {
+	txResults, txSeq := um.events.TxsAfter(um.txSeq)
+	um.txSeq = txSeq
+
+	var txs []txInfo
+	for _, txResult := range txResults {
+		var tx sdk.Tx
+		if err := um.codec.UnmarshalBinary(txResult.Tx, &tx); err != nil {
+			um.logger.Debugf("failed to decode transaction: %v", err)
+			continue
+		}
+
+		txs = append(txs, txInfo{
+			Hash:   txResult.Tx.Hash(),
+			Height: txResult.Height,
+			Tx:     tx,
+			Result: txResult.Result,
+		})
+	}
+
+	return txs
+}
+
+// Exec implements the Monitor interface. It attempts to fetch all successful
+// transactions sourced from each filtered validator since the last execution
+// and find the unbonding delegations and redelegations in them. Shares are
+// converted into tokens using the validator's current exchange rate and
+// compared against the maximum amount and maximum share of the validator's
+// tokens before the undelegation. Upon success, the serialized encoding of the
+// undelegations exceeding either maximum and an ID that is the SHA256 of their
+// transaction hashes will be returned and an error otherwise.
+func (um *UndelegationMonitor) Exec() (resp, id []byte, err error) {
+	client := um.cm.Next()
+	um.logger.Info("monitoring for large unbonding delegations and redelegations")
+
+	// the first execution only records the latest height so that undelegations
+	// before the monitor started are not reported
+	if um.height == 0 {
+		block, err := fetchBlock(um.cm, um.api, client)
+		if err != nil {
+			um.logger.Errorf("failed to get latest block: %v", err)
+			return nil, nil, errors.Wrap(err, "failed to get latest block")
+		}
+
+		um.height = block.Block.Height
+		if um.events != nil {
+			um.eventTxs()
+		}
+
+		return nil, nil, errors.New("no undelegations since the latest block")
+	}
+
+	vals, err := um.getValidators(client)
+	if err != nil {
+		um.logger.Errorf("failed to get all validators: %v", err)
+		return nil, nil, err
+	}
+
+	filter := um.filters.All()
+
+	filtersMap := make(map[string]struct{}, len(filter))
+	for _, validatorFilter := range filter {
+		filtersMap[validatorFilter.Operator] = struct{}{}
+	}
+
+	valsMap := make(map[string]staketypes.BechValidator)
+	for _, val := range vals {
+		operator := um.api.Operator(val.Owner)
+		if _, ok := filtersMap[operator]; ok {
+			valsMap[operator] = val
+		}
+	}
+
+	var txs []txInfo
+
+	if um.events != nil && um.events.Connected() && um.decodeEvents {
+		txs = um.eventTxs()
+
+		// all received blocks are processed so that a search does not consider
+		// transactions of blocks without any transactions either
+		if blocks := um.events.BlocksAfter(um.height); len(blocks) != 0 {
+			um.height = blocks[len(blocks)-1].Height
+		}
+	} else {
+		for _, validatorFilter := range filter {
+			operator := validatorFilter.Operator
+			if _, ok := valsMap[operator]; !ok {
+				continue
+			}
+
+			valTxs, err := um.searchTxs(client, operator)
+			if err != nil {
+				um.logger.Errorf("failed to search transactions for validator %s: %v", operator, err)
+				return nil, nil, errors.Wrap(err, "failed to search transactions")
+			}
+
+			for _, tx := range valTxs {
+				if tx.Height > um.height {
+					txs = append(txs, tx)
+				}
+			}
+		}
+	}
+
+	for _, tx := range txs {
+		if tx.Height > um.height {
+			um.height = tx.Height
+		}
+	}
+
+	var (
+		undelegations []Undelegation
+		idParts       []string
+	)
+
+	for _, tx := range txs {
+		if !tx.Result.IsOK() || tx.Tx == nil {
+			continue
+		}
+
+		for _, msg := range tx.Tx.GetMsgs() {
+			undelegation, ok := um.parseUndelegation(msg, valsMap)
+			if !ok {
+				continue
+			}
+
+			undelegation.TxHash = tx.Hash.String()
+			undelegation.Height = tx.Height
+			undelegations = append(undelegations, undelegation)
+			idParts = append(idParts, undelegation.TxHash)
+		}
+	}
+
+	if len(undelegations) == 0 {
+		return nil, nil, errors.New("no large undelegations from validators matching filter")
+	}
+
+	raw, err := wire.MarshalJSONIndent(um.codec, undelegations)
+	if err != nil {
+		um.logger.Errorf("failed to serialize undelegations: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to serialize undelegations")
+	}
+
+	rawHash := sha256.Sum256([]byte(strings.Join(idParts, ";")))
+	id = rawHash[:]
+
+	return raw, id, nil
+}
+
+// parseUndelegation returns an Undelegation for a given message if it is an
+// unbonding delegation or redelegation from one of the given validators that
+// exceeds the maximum amount or share. False is returned otherwise.
+func (um *UndelegationMonitor) parseUndelegation(
+	msg sdk.Msg, valsMap map[string]staketypes.BechValidator,
+) (Undelegation, bool) {
+	var (
+		undelegation Undelegation
+		tokens       *sdk.Rat
+	)
+
+	// the gateway API undelegates tokens instead of shares
+	if gm, ok := msg.(gatewayUndelegationMsg); ok {
+		msg, tokens = gm.Msg, &gm.tokens
+	}
+
+	switch msg := msg.(type) {
+	case stake.MsgBeginUnbonding:
+		undelegation = Undelegation{
+			Type:      UndelegationTypeUnbonding,
+			Delegator: msg.DelegatorAddr,
+			Validator: msg.ValidatorAddr,
+			Shares:    msg.SharesAmount,
+		}
+
+	case stake.MsgBeginRedelegate:
+		undelegation = Undelegation{
+			Type:         UndelegationTypeRedelegation,
+			Delegator:    msg.DelegatorAddr,
+			Validator:    msg.ValidatorSrcAddr,
+			DstValidator: msg.ValidatorDstAddr,
+			Shares:       msg.SharesAmount,
+		}
+
+	default:
+		return undelegation, false
+	}
+
+	val, ok := valsMap[um.api.Operator(undelegation.Validator)]
+	if !ok {
+		return undelegation, false
+	}
+
+	exRate := sdk.OneRat()
+	if !val.DelegatorShares.IsZero() {
+		exRate = val.Tokens.Quo(val.DelegatorShares)
+	}
+
+	if tokens != nil {
+		undelegation.Tokens = *tokens
+		undelegation.Shares = sdk.ZeroRat()
+
+		if !exRate.IsZero() {
+			undelegation.Shares = tokens.Quo(exRate)
+		}
+	} else {
+		undelegation.Tokens = undelegation.Shares.Mul(exRate)
+	}
+
+	// the share is relative to the validator's tokens before the undelegation,
+	// i.e. its current tokens along with the undelegated tokens
+	share := float64(100)
+	if before := val.Tokens.Add(undelegation.Tokens); !before.IsZero() {
+		share, _ = undelegation.Tokens.Mul(sdk.NewRat(100)).Quo(before).Float64()
+	}
+
+	undelegation.Share = fmt.Sprintf("%.2f%%", share)
+
+	exceedsAmount := um.maxAmount > 0 && undelegation.Tokens.GT(sdk.NewRat(um.maxAmount))
+	exceedsShare := um.maxShare > 0 && share > um.maxShare
+
+	return undelegation, exceedsAmount || exceedsShare
+}
+
+// LowSelfDelegation defines a structure for containing a filtered validator
+// whose self-delegation has fallen within the safety margin of, or below, the
+// minimum self-delegation.
+type LowSelfDelegation struct {
+	Operator          string  `json:"operator"`
+	SelfDelegation    sdk.Rat `json:"self_delegation"`
+	MinSelfDelegation int64   `json:"min_self_delegation"`
+	Threshold         sdk.Rat `json:"threshold"`
+	BelowMinimum      bool    `json:"below_minimum"`
+}
+
+// SelfDelegationMonitor defines a monitor responsible for monitoring when the
+// self-delegation of filtered validators approaches or falls below a minimum.
+//
+// NOTE: The minimum self-delegation is configured per validator as it is not a
+// validator parameter in the currently supported SDK version.
+type SelfDelegationMonitor struct {
+	*baseStakingMonitor
+	minSelfDelegation int64
+	margin            sdk.Rat
+}
+
+// NewSelfDelegationMonitor returns a reference to a new SelfDelegationMonitor.
+func NewSelfDelegationMonitor(logger core.Logger, cfg config.Config, deps Deps, name, memo string) *SelfDelegationMonitor {
+	margin := new(big.Rat).SetFloat64(1 + cfg.Staking.SelfDelegationMargin/100)
+
+	return &SelfDelegationMonitor{
+		baseStakingMonitor: newBaseStakingMonitor(logger, cfg, deps, name, memo),
+		minSelfDelegation:  cfg.Staking.MinSelfDelegation,
+		margin:             sdk.Rat{Rat: margin},
+	}
+}
+
+// getSelfDelegationShares returns the shares a validator's owner has delegated
+// to itself. A missing delegation, i.e. a not found status or an empty
+// response, is treated as having no shares.
+func (sm baseStakingMonitor) getSelfDelegationShares(client, operator string) (sdk.Rat, error) {
+	path, err := sm.api.SelfDelegationPath(operator)
+	if err != nil {
+		return sdk.Rat{}, err
+	}
+
+	resp, err := sm.cm.Request(client+path, core.RequestGET, nil)
+	if core.IsNotFound(err) {
+		return sdk.ZeroRat(), nil
+	} else if err != nil {
+		return sdk.Rat{}, err
+	}
+
+	return sm.api.DecodeSelfDelegationShares(resp)
+}
+
+// Exec implements the Monitor interface. It attempts to fetch the
+// self-delegation of each filtered validator and convert it into tokens using
+// the validator's current exchange rate. Upon success, the serialized encoding
+// of validators whose self-delegation is below their minimum plus the safety
+// margin will be returned along with an ID that is the SHA256 of each
+// validator operator and whether it is below the minimum so that a changing
+// self-delegation does not re-trigger an alert. An error is returned otherwise.
+func (sdm *SelfDelegationMonitor) Exec() (resp, id []byte, err error) {
+	client := sdm.cm.Next()
+	sdm.logger.Info("monitoring for validators with low self-delegation")
+
+	vals, err := sdm.getValidators(client)
+	if err != nil {
+		sdm.logger.Errorf("failed to get all validators: %v", err)
+		return nil, nil, err
+	}
+
+	valsMap := make(map[string]staketypes.BechValidator, len(vals))
+	for _, val := range vals {
+		valsMap[sdm.api.Operator(val.Owner)] = val
+	}
+
+	var (
+		lowSelfDelegations []LowSelfDelegation
+		idParts            []string
+	)
+
+	for _, validatorFilter := range sdm.filters.All() {
+		val, ok := valsMap[validatorFilter.Operator]
+		if !ok {
+			continue
+		}
+
+		shares, err := sdm.getSelfDelegationShares(client, validatorFilter.Operator)
+		if err != nil {
+			sdm.logger.Errorf("failed to get self-delegation for validator %s: %v", validatorFilter.Operator, err)
+			return nil, nil, errors.Wrap(err, "failed to get self-delegation")
+		}
+
+		tokens := shares
+		if !val.DelegatorShares.IsZero() {
+			tokens = shares.Mul(val.Tokens).Quo(val.DelegatorShares)
+		}
+
+		minSelfDelegation := validatorFilter.MinSelfDelegation
+		if minSelfDelegation == 0 {
+			minSelfDelegation = sdm.minSelfDelegation
+		}
+
+		threshold := sdk.NewRat(minSelfDelegation).Mul(sdm.margin).Round(100)
+
+		if !tokens.LT(threshold) {
+			continue
+		}
+
+		lowSelfDelegation := LowSelfDelegation{
+			Operator:          validatorFilter.Operator,
+			SelfDelegation:    tokens,
+			MinSelfDelegation: minSelfDelegation,
+			Threshold:         threshold,
+			BelowMinimum:      tokens.LT(sdk.NewRat(minSelfDelegation)),
+		}
+
+		lowSelfDelegations = append(lowSelfDelegations, lowSelfDelegation)
+		idParts = append(idParts, fmt.Sprintf("%s/%t", validatorFilter.Operator, lowSelfDelegation.BelowMinimum))
+	}
+
+	if len(lowSelfDelegations) == 0 {
+		return nil, nil, errors.New("no validators matching filter with low self-delegation")
+	}
+
+	raw, err := wire.MarshalJSONIndent(sdm.codec, lowSelfDelegations)
+	if err != nil {
+		sdm.logger.Errorf("failed to serialize low self-delegations: %v", err)
+		return nil, nil, errors.Wrap(err, "failed to serialize low self-delegations")
+	}
+
+	rawHash := sha256.Sum256([]byte(strings.Join(idParts, ";")))
+	id = rawHash[:]
+
+	return raw, id, nil
+}
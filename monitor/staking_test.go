@@ -2,8 +2,10 @@ package monitor_test
 
 import (
 	"crypto/sha256"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/alexanderbez/titan/config"
@@ -11,14 +13,22 @@ import (
 	"github.com/alexanderbez/titan/monitor"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/wire"
+	"github.com/cosmos/cosmos-sdk/x/auth"
 	"github.com/cosmos/cosmos-sdk/x/stake"
 	staketypes "github.com/cosmos/cosmos-sdk/x/stake/types"
 
 	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
 )
 
 func newStakingTestCodec() *wire.Codec {
 	codec := wire.NewCodec()
+	sdk.RegisterWire(codec)
+	auth.RegisterWire(codec)
 	stake.RegisterWire(codec)
 	wire.RegisterCrypto(codec)
 
@@ -30,7 +40,7 @@ func newTestJailedValidatorMonitor(t *testing.T, cfg config.Config) *monitor.Jai
 	require.NoError(t, err)
 
 	return monitor.NewJailedValidatorMonitor(
-		logger, cfg, monitor.JailedValidatorMonitorName, monitor.JailedValidatorMonitorMemo,
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.JailedValidatorMonitorName, monitor.JailedValidatorMonitorMemo,
 	)
 }
 
@@ -153,3 +163,479 @@ func TestMatchingJailedValidators(t *testing.T) {
 	require.Equal(t, exID, id)
 	require.Len(t, vals, len(validators))
 }
+
+func newTestConsensusKeyMonitor(t *testing.T, cfg config.Config) *monitor.ConsensusKeyMonitor {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	return monitor.NewConsensusKeyMonitor(
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.ConsensusKeyMonitorName, monitor.ConsensusKeyMonitorMemo,
+	)
+}
+
+func TestConsensusKeyChanges(t *testing.T) {
+	codec := newStakingTestCodec()
+
+	opAddr1, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	pubKey1 := ed25519.GenPrivKey().PubKey()
+	pubKey2 := ed25519.GenPrivKey().PubKey()
+
+	val, err := stake.NewValidator(opAddr1, pubKey1, stake.Description{}).Bech32Validator()
+	require.NoError(t, err)
+
+	raw, err := codec.MarshalJSON([]staketypes.BechValidator{val})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+	}))
+	defer ts.Close()
+
+	clients := []string{ts.URL}
+	cfg := config.Config{
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Operator: opAddr1.String(), Address: pubKey2.Address().String()},
+			},
+		},
+		Network: config.NetworkConfig{Clients: clients},
+	}
+
+	// the key change is reported on every execution when not followed
+	ckm := newTestConsensusKeyMonitor(t, cfg)
+
+	for i := 0; i < 2; i++ {
+		resp, id, err := ckm.Exec()
+		require.NoError(t, err)
+
+		var keyChanges []monitor.ConsensusKeyChange
+		err = codec.UnmarshalJSON(resp, &keyChanges)
+		require.NoError(t, err)
+
+		rawHash := sha256.Sum256(resp)
+		exID := rawHash[:]
+
+		require.Equal(t, exID, id)
+		require.Len(t, keyChanges, 1)
+		require.Equal(t, pubKey2.Address().String(), keyChanges[0].ConfiguredAddress)
+		require.Equal(t, pubKey1.Address().String(), keyChanges[0].CurrentAddress)
+		require.False(t, keyChanges[0].Followed)
+	}
+
+	// the key change is reported once when followed
+	cfg.Staking.FollowConsensusKey = true
+	ckm = newTestConsensusKeyMonitor(t, cfg)
+
+	resp, _, err := ckm.Exec()
+	require.NoError(t, err)
+
+	var keyChanges []monitor.ConsensusKeyChange
+	err = codec.UnmarshalJSON(resp, &keyChanges)
+	require.NoError(t, err)
+	require.True(t, keyChanges[0].Followed)
+
+	resp, id, err := ckm.Exec()
+	require.Error(t, err)
+	require.Nil(t, resp)
+	require.Nil(t, id)
+}
+
+type testTxInfo struct {
+	Hash   cmn.HexBytes           `json:"hash"`
+	Height int64                  `json:"height"`
+	Tx     sdk.Tx                 `json:"tx"`
+	Result abci.ResponseDeliverTx `json:"result"`
+}
+
+func newTestUndelegationMonitor(t *testing.T, cfg config.Config) *monitor.UndelegationMonitor {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	return monitor.NewUndelegationMonitor(
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.UndelegationMonitorName, monitor.UndelegationMonitorMemo,
+	)
+}
+
+func newTestUndelegationServer(
+	t *testing.T, val staketypes.Validator, txs *[]testTxInfo, height *int64,
+) *httptest.Server {
+	codec := newStakingTestCodec()
+
+	bechVal, err := val.Bech32Validator()
+	require.NoError(t, err)
+
+	rawVals, err := codec.MarshalJSON([]staketypes.BechValidator{bechVal})
+	require.NoError(t, err)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/blocks/latest" {
+			rawBlock, err := newSlashingTestCodec().MarshalJSON(&ctypes.ResultBlock{
+				Block: tmtypes.MakeBlock(*height, nil, nil, nil),
+			})
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			w.Write(rawBlock)
+			return
+		}
+
+		if r.URL.Path != "/txs" {
+			w.WriteHeader(http.StatusOK)
+			w.Write(rawVals)
+			return
+		}
+
+		rawTxs, err := codec.MarshalJSON(*txs)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(rawTxs)
+	}))
+}
+
+func TestUndelegations(t *testing.T) {
+	codec := newStakingTestCodec()
+
+	opAddr1, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	opAddr2, err := sdk.AccAddressFromBech32("cosmosaccaddr1y2z20pwqu5qpclque3pqkguruvheum2djtzjw3")
+	require.NoError(t, err)
+
+	// the validator has 100000 tokens before both undelegations
+	val := stake.NewValidator(opAddr1, ed25519.GenPrivKey().PubKey(), stake.Description{})
+	val.Tokens = sdk.NewRat(78000)
+	val.DelegatorShares = sdk.NewRat(39000)
+
+	txs := []testTxInfo{
+		// 2000 tokens (2.5%) unbonded
+		testTxInfo{
+			Hash:   cmn.HexBytes{0x01},
+			Height: 10,
+			Tx:     auth.StdTx{Msgs: []sdk.Msg{stake.NewMsgBeginUnbonding(opAddr2, opAddr1, sdk.NewRat(1000))}},
+		},
+		// 20000 tokens (20.4%) redelegated
+		testTxInfo{
+			Hash:   cmn.HexBytes{0x02},
+			Height: 11,
+			Tx:     auth.StdTx{Msgs: []sdk.Msg{stake.NewMsgBeginRedelegate(opAddr2, opAddr1, opAddr2, sdk.NewRat(10000))}},
+		},
+		// failed transactions are ignored
+		testTxInfo{
+			Hash:   cmn.HexBytes{0x03},
+			Height: 12,
+			Tx:     auth.StdTx{Msgs: []sdk.Msg{stake.NewMsgBeginUnbonding(opAddr2, opAddr1, sdk.NewRat(40000))}},
+			Result: abci.ResponseDeliverTx{Code: 1},
+		},
+	}
+
+	height := int64(9)
+	ts := newTestUndelegationServer(t, val, &txs, &height)
+	defer ts.Close()
+
+	clients := []string{ts.URL}
+	cfg := config.Config{
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Operator: opAddr1.String()},
+			},
+		},
+		Network: config.NetworkConfig{Clients: clients},
+		Staking: config.Staking{MaxUndelegationShare: 10},
+	}
+
+	udm := newTestUndelegationMonitor(t, cfg)
+
+	// the first execution starts at the latest block
+	resp, id, err := udm.Exec()
+	require.Error(t, err)
+	require.Nil(t, resp)
+	require.Nil(t, id)
+
+	resp, id, err = udm.Exec()
+	require.NoError(t, err)
+
+	var undelegations []monitor.Undelegation
+	err = codec.UnmarshalJSON(resp, &undelegations)
+	require.NoError(t, err)
+
+	rawHash := sha256.Sum256([]byte(cmn.HexBytes{0x02}.String()))
+	exID := rawHash[:]
+
+	require.Equal(t, exID, id)
+	require.Len(t, undelegations, 1)
+	require.Equal(t, monitor.UndelegationTypeRedelegation, undelegations[0].Type)
+	require.Equal(t, "20.41%", undelegations[0].Share)
+	require.True(t, undelegations[0].Tokens.Equal(sdk.NewRat(20000)))
+
+	// processed transactions are not reported again
+	resp, id, err = udm.Exec()
+	require.Error(t, err)
+	require.Nil(t, resp)
+	require.Nil(t, id)
+
+	// only new transactions are reported
+	txs = append(txs, testTxInfo{
+		Hash:   cmn.HexBytes{0x04},
+		Height: 13,
+		Tx:     auth.StdTx{Msgs: []sdk.Msg{stake.NewMsgBeginUnbonding(opAddr2, opAddr1, sdk.NewRat(10000))}},
+	})
+
+	resp, id, err = udm.Exec()
+	require.NoError(t, err)
+
+	err = codec.UnmarshalJSON(resp, &undelegations)
+	require.NoError(t, err)
+
+	rawHash = sha256.Sum256([]byte(cmn.HexBytes{0x04}.String()))
+	exID = rawHash[:]
+
+	require.Equal(t, exID, id)
+	require.Len(t, undelegations, 1)
+	require.Equal(t, int64(13), undelegations[0].Height)
+
+	// all undelegations exceed the maximum amount
+	cfg.Staking = config.Staking{MaxUndelegationAmount: 1000}
+	udm = newTestUndelegationMonitor(t, cfg)
+
+	_, _, err = udm.Exec()
+	require.Error(t, err)
+
+	resp, _, err = udm.Exec()
+	require.NoError(t, err)
+
+	err = codec.UnmarshalJSON(resp, &undelegations)
+	require.NoError(t, err)
+	require.Len(t, undelegations, 3)
+
+	// no undelegations exceed the maximum amount
+	cfg.Staking = config.Staking{MaxUndelegationAmount: 50000}
+	udm = newTestUndelegationMonitor(t, cfg)
+
+	_, _, err = udm.Exec()
+	require.Error(t, err)
+
+	resp, id, err = udm.Exec()
+	require.Error(t, err)
+	require.Nil(t, resp)
+	require.Nil(t, id)
+}
+
+func TestUndelegationsFirstPoll(t *testing.T) {
+	codec := newStakingTestCodec()
+
+	opAddr1, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	opAddr2, err := sdk.AccAddressFromBech32("cosmosaccaddr1y2z20pwqu5qpclque3pqkguruvheum2djtzjw3")
+	require.NoError(t, err)
+
+	val := stake.NewValidator(opAddr1, ed25519.GenPrivKey().PubKey(), stake.Description{})
+	val.Tokens = sdk.NewRat(100000)
+	val.DelegatorShares = sdk.NewRat(100000)
+
+	txs := []testTxInfo{
+		testTxInfo{
+			Hash:   cmn.HexBytes{0x01},
+			Height: 10,
+			Tx:     auth.StdTx{Msgs: []sdk.Msg{stake.NewMsgBeginUnbonding(opAddr2, opAddr1, sdk.NewRat(50000))}},
+		},
+	}
+
+	height := int64(10)
+	ts := newTestUndelegationServer(t, val, &txs, &height)
+	defer ts.Close()
+
+	cfg := config.Config{
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{
+				config.ValidatorFilter{Operator: opAddr1.String()},
+			},
+		},
+		Network: config.NetworkConfig{Clients: []string{ts.URL}},
+		Staking: config.Staking{MaxUndelegationShare: 10},
+	}
+
+	udm := newTestUndelegationMonitor(t, cfg)
+
+	// undelegations up to the latest block are not reported
+	resp, id, err := udm.Exec()
+	require.Error(t, err)
+	require.Nil(t, resp)
+	require.Nil(t, id)
+
+	resp, id, err = udm.Exec()
+	require.Error(t, err)
+	require.Nil(t, resp)
+	require.Nil(t, id)
+
+	txs = append(txs, testTxInfo{
+		Hash:   cmn.HexBytes{0x02},
+		Height: 11,
+		Tx:     auth.StdTx{Msgs: []sdk.Msg{stake.NewMsgBeginUnbonding(opAddr2, opAddr1, sdk.NewRat(20000))}},
+	})
+
+	resp, id, err = udm.Exec()
+	require.NoError(t, err)
+
+	var undelegations []monitor.Undelegation
+	err = codec.UnmarshalJSON(resp, &undelegations)
+	require.NoError(t, err)
+
+	rawHash := sha256.Sum256([]byte(cmn.HexBytes{0x02}.String()))
+	require.Equal(t, rawHash[:], id)
+	require.Len(t, undelegations, 1)
+	require.Equal(t, int64(11), undelegations[0].Height)
+}
+
+func newTestSelfDelegationServer(t *testing.T, val staketypes.Validator, shares string) *httptest.Server {
+	codec := newStakingTestCodec()
+
+	bechVal, err := val.Bech32Validator()
+	require.NoError(t, err)
+
+	rawVals, err := codec.MarshalJSON([]staketypes.BechValidator{bechVal})
+	require.NoError(t, err)
+
+	rawDelegation := []byte(fmt.Sprintf(`{"shares":"%s"}`, shares))
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+
+		if strings.HasPrefix(r.URL.Path, "/stake/delegators") {
+			w.Write(rawDelegation)
+		} else {
+			w.Write(rawVals)
+		}
+	}))
+}
+
+func newTestSelfDelegationMonitor(t *testing.T, ts *httptest.Server, operator string) *monitor.SelfDelegationMonitor {
+	return newTestSelfDelegationMonitorWithFilter(t, ts, config.ValidatorFilter{Operator: operator})
+}
+
+func newTestSelfDelegationMonitorWithFilter(
+	t *testing.T, ts *httptest.Server, validatorFilter config.ValidatorFilter,
+) *monitor.SelfDelegationMonitor {
+	logger, err := core.CreateBaseLogger("", false)
+	require.NoError(t, err)
+
+	clients := []string{ts.URL}
+	cfg := config.Config{
+		Filters: config.Filters{
+			Validators: []config.ValidatorFilter{validatorFilter},
+		},
+		Network: config.NetworkConfig{Clients: clients},
+		Staking: config.Staking{MinSelfDelegation: 1000, SelfDelegationMargin: 10},
+	}
+
+	return monitor.NewSelfDelegationMonitor(
+		logger, cfg, monitor.NewDeps(cfg, nil, nil), monitor.SelfDelegationMonitorName, monitor.SelfDelegationMonitorMemo,
+	)
+}
+
+func TestNoLowSelfDelegation(t *testing.T) {
+	opAddr1, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	val := stake.NewValidator(opAddr1, ed25519.GenPrivKey().PubKey(), stake.Description{})
+	val.Tokens = sdk.NewRat(10000)
+	val.DelegatorShares = sdk.NewRat(5000)
+
+	// 600 shares is worth 1200 tokens which is above the 1100 token threshold
+	ts := newTestSelfDelegationServer(t, val, "600.0000000000")
+	defer ts.Close()
+
+	sdm := newTestSelfDelegationMonitor(t, ts, opAddr1.String())
+
+	resp, id, err := sdm.Exec()
+	require.Error(t, err)
+	require.Nil(t, resp)
+	require.Nil(t, id)
+}
+
+func TestLowSelfDelegation(t *testing.T) {
+	codec := newStakingTestCodec()
+
+	opAddr1, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	val := stake.NewValidator(opAddr1, ed25519.GenPrivKey().PubKey(), stake.Description{})
+	val.Tokens = sdk.NewRat(10000)
+	val.DelegatorShares = sdk.NewRat(5000)
+
+	// 525 shares is worth 1050 tokens which is within the safety margin
+	ts := newTestSelfDelegationServer(t, val, "525.0000000000")
+	defer ts.Close()
+
+	sdm := newTestSelfDelegationMonitor(t, ts, opAddr1.String())
+
+	resp, id, err := sdm.Exec()
+	require.NoError(t, err)
+	require.NotNil(t, id)
+
+	var lowSelfDelegations []monitor.LowSelfDelegation
+	err = codec.UnmarshalJSON(resp, &lowSelfDelegations)
+	require.NoError(t, err)
+
+	require.Len(t, lowSelfDelegations, 1)
+	require.True(t, lowSelfDelegations[0].SelfDelegation.Equal(sdk.NewRat(1050)))
+	require.False(t, lowSelfDelegations[0].BelowMinimum)
+
+	// falling below the minimum results in a new ID
+	ts2 := newTestSelfDelegationServer(t, val, "400.0000000000")
+	defer ts2.Close()
+
+	sdm = newTestSelfDelegationMonitor(t, ts2, opAddr1.String())
+
+	resp, id2, err := sdm.Exec()
+	require.NoError(t, err)
+	require.NotEqual(t, id, id2)
+
+	err = codec.UnmarshalJSON(resp, &lowSelfDelegations)
+	require.NoError(t, err)
+	require.True(t, lowSelfDelegations[0].BelowMinimum)
+}
+
+func TestValidatorMinSelfDelegation(t *testing.T) {
+	codec := newStakingTestCodec()
+
+	opAddr1, err := sdk.AccAddressFromBech32("cosmosaccaddr1chchjxgackcqkn9fqgpsc4n9xamx4flgndapzg")
+	require.NoError(t, err)
+
+	val := stake.NewValidator(opAddr1, ed25519.GenPrivKey().PubKey(), stake.Description{})
+	val.Tokens = sdk.NewRat(10000)
+	val.DelegatorShares = sdk.NewRat(5000)
+
+	// 600 shares is worth 1200 tokens which is above the default minimum but
+	// below the validator's own minimum
+	ts := newTestSelfDelegationServer(t, val, "600.0000000000")
+	defer ts.Close()
+
+	sdm := newTestSelfDelegationMonitorWithFilter(t, ts, config.ValidatorFilter{
+		Operator:          opAddr1.String(),
+		MinSelfDelegation: 2000,
+	})
+
+	resp, id, err := sdm.Exec()
+	require.NoError(t, err)
+	require.NotNil(t, id)
+
+	var lowSelfDelegations []monitor.LowSelfDelegation
+	err = codec.UnmarshalJSON(resp, &lowSelfDelegations)
+	require.NoError(t, err)
+
+	require.Len(t, lowSelfDelegations, 1)
+	require.Equal(t, int64(2000), lowSelfDelegations[0].MinSelfDelegation)
+	require.True(t, lowSelfDelegations[0].Threshold.Equal(sdk.NewRat(2200)))
+	require.True(t, lowSelfDelegations[0].BelowMinimum)
+}
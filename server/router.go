@@ -8,17 +8,26 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// createRouter creates the server's router. Routes of a monitored chain are
+// namespaced by its chain ID (e.g. /chains/cosmoshub-4/executions/latest). When
+// monitoring a single chain, its routes are also served without a namespace.
+// Prometheus metrics of all chains are served under /metrics.
 func (srvr *Server) createRouter() *mux.Router {
 	router := mux.NewRouter()
-	router.HandleFunc("/executions/latest", srvr.GetLatestExecution()).Methods("GET")
+	router.Handle("/metrics", core.DefaultMetrics().Handler()).Methods("GET")
+	router.HandleFunc("/chains/{chainID}/executions/latest", srvr.GetChainLatestExecution()).Methods("GET")
+
+	if len(srvr.chainIDs) == 1 {
+		router.HandleFunc("/executions/latest", srvr.GetLatestExecution(srvr.chainIDs[0])).Methods("GET")
+	}
 
 	return router
 }
 
-// GetLatestExecution returns the latest monitor execution.
-func (srvr *Server) GetLatestExecution() func(http.ResponseWriter, *http.Request) {
+// GetLatestExecution returns the latest monitor execution of a given chain.
+func (srvr *Server) GetLatestExecution(chainID string) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		value, err := srvr.db.Get(core.BadgerMonitorsNamespace, manager.MonitorExecKey)
+		value, err := srvr.db.Get(core.ChainNamespace(chainID, core.BadgerMonitorsNamespace), manager.MonitorExecKey)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
@@ -29,3 +38,28 @@ func (srvr *Server) GetLatestExecution() func(http.ResponseWriter, *http.Request
 		w.Write(value)
 	}
 }
+
+// GetChainLatestExecution returns the latest monitor execution of the chain
+// given by the route's chain ID. Chains that are not monitored are not found.
+func (srvr *Server) GetChainLatestExecution() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chainID := mux.Vars(r)["chainID"]
+		if !srvr.monitorsChain(chainID) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		srvr.GetLatestExecution(chainID)(w, r)
+	}
+}
+
+// monitorsChain returns true if a chain with a given non-empty ID is monitored.
+func (srvr *Server) monitorsChain(chainID string) bool {
+	for _, id := range srvr.chainIDs {
+		if id != "" && id == chainID {
+			return true
+		}
+	}
+
+	return false
+}
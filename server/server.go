@@ -10,11 +10,12 @@ import (
 )
 
 // Server is a simple wrapper around an embedded HTTP server with a logger and
-// database.
+// database. The monitored chain IDs are kept to namespace routes per chain.
 type Server struct {
 	*http.Server
-	db     core.DB
-	logger core.Logger
+	db       core.DB
+	logger   core.Logger
+	chainIDs []string
 }
 
 // CreateServer attempts to start a RESTful JSON HTTP service. If the server
@@ -25,6 +26,10 @@ func CreateServer(cfg config.Config, db core.DB, logger core.Logger) (*Server, e
 		logger: logger.With("module", "server"),
 	}
 
+	for _, chainCfg := range cfg.ChainConfigs() {
+		srvr.chainIDs = append(srvr.chainIDs, chainCfg.ChainID)
+	}
+
 	srvr.Server = &http.Server{
 		Addr:         cfg.Network.ListenAddr,
 		Handler:      srvr.createRouter(),